@@ -2,93 +2,164 @@ package vault
 
 import (
 	"bytes"
-	"crypto/tls"
+	"context"
 	"crypto/x509"
-	"errors"
+	"io"
 	"io/ioutil"
 	"net/http"
 )
 
+type ResponseError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *ResponseError) Error() string {
+	return e.Status
+}
+
+func isResponseError(err error) bool {
+	_, ok := err.(*ResponseError)
+	return ok
+}
+
 type httpActions struct {
 	httpClient *http.Client
+	reload     *reloadingTransport
+	limiter    *requestLimiter
+	breaker    *circuitBreaker
 }
 
-func (h httpActions) get(url, token string) ([]byte, error) {
-	request, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
+func (h httpActions) close() {
+	if h.reload != nil {
+		h.reload.close()
 	}
+}
 
-	request.Header.Add("X-Vault-Token", token)
-	response, err := h.httpClient.Do(request)
-	if err != nil {
-		return nil, err
-	}
+func (h httpActions) get(url, token string) ([]byte, error) {
+	return h.request(context.Background(), "GET", url, token, nil)
+}
 
-	if response.StatusCode != 200 {
-		return nil, errors.New(response.Status)
-	}
+func (h httpActions) post(url, token string, credentials []byte) ([]byte, error) {
+	return h.request(context.Background(), "POST", url, token, credentials)
+}
 
-	body, err := ioutil.ReadAll(response.Body)
+func (h httpActions) request(ctx context.Context, method, url, token string, data []byte) ([]byte, error) {
+	body, _, err := h.send(ctx, method, url, token, data, nil)
 	if err != nil {
 		return nil, err
 	}
-
-	defer response.Body.Close()
 	return body, nil
 }
 
-func (h httpActions) post(url, token string, credentials []byte) ([]byte, error) {
-	request, err := http.NewRequest("POST", url, bytes.NewBuffer(credentials))
+func (h httpActions) send(ctx context.Context, method, url, token string, data []byte, header http.Header) ([]byte, http.Header, error) {
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, url, body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	request = request.WithContext(ctx)
 
+	for key, values := range header {
+		for _, value := range values {
+			request.Header.Add(key, value)
+		}
+	}
 	if token != "" {
 		request.Header.Add("X-Vault-Token", token)
 	}
 
+	if h.breaker != nil {
+		done, err := h.breaker.allow(request.URL.Host)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		b, responseHeader, err := h.do(ctx, request)
+		done(breakerResult(ctx, err))
+		return b, responseHeader, err
+	}
+	return h.do(ctx, request)
+}
+
+func (h httpActions) do(ctx context.Context, request *http.Request) ([]byte, http.Header, error) {
+	if h.limiter != nil {
+		release, err := h.limiter.acquire(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer release()
+	}
+
 	response, err := h.httpClient.Do(request)
 	if err != nil {
-		return nil, err
-	}
-	if response.StatusCode != 200 {
-		return nil, errors.New(response.Status)
+		return nil, nil, err
 	}
+	defer response.Body.Close()
 
-	body, err := ioutil.ReadAll(response.Body)
+	b, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return nil, err
+		return nil, response.Header, err
 	}
 
-	defer response.Body.Close()
-	return body, nil
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return b, response.Header, &ResponseError{StatusCode: response.StatusCode, Status: response.Status}
+	}
+	return b, response.Header, nil
 }
 
-func newActions(certPath string) (*httpActions, error) {
-	certPool, err := generateCertPool(certPath)
+func newActions(options *ClientOptions) (*httpActions, error) {
+	if options.HTTPClient != nil {
+		httpClient := *options.HTTPClient
+		if len(options.Middleware) > 0 {
+			transport := httpClient.Transport
+			if transport == nil {
+				transport = http.DefaultTransport
+			}
+			httpClient.Transport = chain(transport, options.Middleware)
+		}
+		return &httpActions{
+			httpClient: &httpClient,
+			limiter:    newRequestLimiter(options.Limit),
+			breaker:    newCircuitBreaker(options.Breaker),
+		}, nil
+	}
+
+	tlsConfig, err := newTLSConfig(options)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, err := newTransport(options)
 	if err != nil {
 		return nil, err
 	}
+	transport.TLSClientConfig = tlsConfig
 
-	httpClient := &http.Client {
-		Transport: &http.Transport {
-			TLSClientConfig: &tls.Config{RootCAs: certPool},
-		},
+	actions := &httpActions{
+		httpClient: &http.Client{},
+		limiter:    newRequestLimiter(options.Limit),
+		breaker:    newCircuitBreaker(options.Breaker),
 	}
+	var roundTripper http.RoundTripper = transport
+	if options.TLS.ReloadInterval > 0 {
+		actions.reload = newReloadingTransport(options, transport)
+		roundTripper = actions.reload
+	}
+	actions.httpClient.Transport = chain(roundTripper, options.Middleware)
 
-	return &httpActions{httpClient:httpClient}, nil
+	return actions, nil
 }
 
 func generateCertPool(filepath string) (*x509.CertPool, error) {
-	caCert, err := ioutil.ReadFile(filepath)
-	if err != nil {
+	caCertPool := x509.NewCertPool()
+	if err := appendCertFile(caCertPool, filepath, nil); err != nil {
 		return nil, err
 	}
 
-	caCertPool := x509.NewCertPool()
-	caCertPool.AppendCertsFromPEM(caCert)
-
 	return caCertPool, nil
 }
-
@@ -1,6 +1,7 @@
 package vault
 
 import (
+	"context"
 	"crypto/tls"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
@@ -12,14 +13,25 @@ import (
 
 const certTestActions = `
 -----BEGIN CERTIFICATE-----
-MIIFMzCCAxugAwIBAgIUFba3dcyDp9qymQCgryrpvUfUaFswDQYJKoZIhvcNAQEL
-BQAwITEfMB0GA1UEAxMWU0VBUkNIIE1BSUwuUlUgUm9vdCBDQTAeFw0xOTA3MDMx
-MzM5MjVaFw0yOTA2MzAxMzM5NTNaMCExHzAdBgNVBAMTFlNFQVJDSCBNQUlMLlJV
+MIIBezCCASCgAwIBAgIIGN/p8ccL39wwCgYIKoZIzj0EAwIwIDEeMBwGA1UEAxMV
+R08tVkFVTFQgVEVTVCBSb290IENBMCAXDTIwMDEwMTAwMDAwMFoYDzIxMjAwMTAx
+MDAwMDAwWjAgMR4wHAYDVQQDExVHTy1WQVVMVCBURVNUIFJvb3QgQ0EwWTATBgcq
+hkjOPQIBBggqhkjOPQMBBwNCAAQN0oZ1PcKUtIHGWL7rruNdrLCtl6K5FIABXmQD
+ZuLvKh19qUIZ+/e0p1DZAUAsGkDv5zz9NsC12SimlQ9Hwhp8o0IwQDAOBgNVHQ8B
+Af8EBAMCAgQwDwYDVR0TAQH/BAUwAwEB/zAdBgNVHQ4EFgQU3V/Vl8lWfI4Ff8PO
+CgVs5Gpp2dQwCgYIKoZIzj0EAwIDSQAwRgIhAJ/xVjBSG6ClWWiIo5e8aDkw5WcA
+L/otr5Q7OTTJS4L4AiEAvC+BHGg4iXhfwXKIaJqzZqf8/OdKxXsAOv1OjaFOSpY=
 -----END CERTIFICATE-----
 -----BEGIN CERTIFICATE-----
-MIIFhjCCA26gAwIBAgIUYswAasjzKm+hGa6uYIjAeAPnOBowDQYJKoZIhvcNAQEL
-BQAwITEfMB0GA1UEAxMWU0VBUkNIIE1BSUwuUlUgUm9vdCBDQTAeFw0xOTA3MTkx
-NjUxMjFaFw0yNDA3MTcxNjUxNTFaMC4xLDAqBgNVBAMTI1NFQVJDSCBNQUlMLlJV
+MIIBizCCATCgAwIBAgIIGN/p8ccR2kEwCgYIKoZIzj0EAwIwKDEmMCQGA1UEAxMd
+R08tVkFVTFQgVEVTVCBJbnRlcm1lZGlhdGUgQ0EwIBcNMjAwMTAxMDAwMDAwWhgP
+MjEyMDAxMDEwMDAwMDBaMCgxJjAkBgNVBAMTHUdPLVZBVUxUIFRFU1QgSW50ZXJt
+ZWRpYXRlIENBMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEqL2EEFXkrEhtb3FZ
+CLO+N0vZv05eAxV2gkCItV+W4AH7GJXuSDDRfpjSsxMZAKXUsVcIIcpfJ1z1S7XB
+O5KgGKNCMEAwDgYDVR0PAQH/BAQDAgIEMA8GA1UdEwEB/wQFMAMBAf8wHQYDVR0O
+BBYEFAsXTLHEQWS4K11IKke4Vgy3/11BMAoGCCqGSM49BAMCA0kAMEYCIQCdmaS0
+bNsXKmsOdbrfAbFR+t8RPHkkBobYIRr+sp0ruAIhAPfjOnGYWEV+2vu6wcMUV787
+xkt/Y5JMM6Kxr2UdY0TX
 -----END CERTIFICATE-----
 `
 
@@ -56,14 +68,24 @@ func TestNewActionPositive1(t *testing.T) {
 	}
 
 	expect := &httpActions{httpClient: httpClient}
-	actual, err := newActions(file.Name())
+	actual, err := newActions(&ClientOptions{CertFilePath: file.Name()})
 
 	assert.Nil(t, err)
-	assert.Equal(t, expect, actual)
+	assertActionsEqual(t, expect, actual)
+}
+
+// CertPool keeps certificates behind closures, so pools are compared by subjects.
+func assertActionsEqual(t *testing.T, expect, actual *httpActions) {
+	expectConfig := expect.httpClient.Transport.(*http.Transport).TLSClientConfig.Clone()
+	actualConfig := actual.httpClient.Transport.(*http.Transport).TLSClientConfig.Clone()
+	assert.Equal(t, expectConfig.RootCAs.Subjects(), actualConfig.RootCAs.Subjects())
+
+	expectConfig.RootCAs, actualConfig.RootCAs = nil, nil
+	assert.Equal(t, expectConfig, actualConfig)
 }
 
 func TestNewActionNegative1(t *testing.T) {
-	actual, err := newActions("path_not_exist/file.txt")
+	actual, err := newActions(&ClientOptions{CertFilePath: "path_not_exist/file.txt"})
 
 	assert.Nil(t, actual)
 	assert.Error(t, err, "")
@@ -86,7 +108,7 @@ func TestActionGetPositive1(t *testing.T) {
 	b := []byte(certTestActions)
 	_ = ioutil.WriteFile(file.Name(), b, 0644)
 
-	action, _ := newActions(file.Name())
+	action, _ := newActions(&ClientOptions{CertFilePath: file.Name()})
 	_, err := action.get(testServer.URL, "test_token")
 	assert.Nil(t, err)
 
@@ -110,9 +132,40 @@ func TestActionGetNegative1(t *testing.T) {
 	b := []byte(certTestActions)
 	_ = ioutil.WriteFile(file.Name(), b, 0644)
 
-	action, _ := newActions(file.Name())
+	action, _ := newActions(&ClientOptions{CertFilePath: file.Name()})
 	resp, err := action.get(testServer.URL, "test_token")
 
 	assert.Nil(t, resp)
 	assert.Error(t, err, "")
 }
+
+func TestActionRequestPositive1(t *testing.T) {
+	testHandler := func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, req.Method, "POST")
+		assert.Equal(t, req.Header.Get("X-Vault-Token"), "")
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	testServer := httptest.NewServer(http.HandlerFunc(testHandler))
+	defer testServer.Close()
+
+	action := &httpActions{httpClient: testServer.Client()}
+	resp, err := action.request(context.Background(), "POST", testServer.URL, "", nil)
+
+	assert.Nil(t, err)
+	assert.Empty(t, resp)
+}
+
+func TestActionRequestNegative1(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer testServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	action := &httpActions{httpClient: testServer.Client()}
+	resp, err := action.request(ctx, "GET", testServer.URL, "test_token", nil)
+
+	assert.Nil(t, resp)
+	assert.Error(t, err, "")
+}
@@ -2,8 +2,10 @@ package vault
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"path"
+	"time"
 )
 
 const (
@@ -14,11 +16,20 @@ const (
 	authLink   = "auth/approle/login"
 	updateLink = "auth/token/renew-self"
 	lookupLink = "auth/token/lookup-self"
+	revokeLink = "auth/token/revoke-self"
 )
 
 type ClientApi struct {
-	Host string
-	Port string
+	Address string
+	Host    string
+	Port    string
+
+	Addresses      []string
+	ProbeInterval  time.Duration
+	StandbyReads   bool
+	ReadAfterWrite bool
+	Agent          bool
+	Namespace      string
 
 	Version    string
 	AuthLink   string
@@ -27,12 +38,67 @@ type ClientApi struct {
 }
 
 func (c ClientApi) baseUrl() string {
-	u, _ := url.Parse(fmt.Sprintf("%s:%s", c.Host, c.Port))
+	u := addressUrl(c.addresses()[0])
 	u.Path = path.Join(u.Path, c.Version)
 
 	return u.String()
 }
 
+func (c ClientApi) addresses() []string {
+	if len(c.Addresses) > 0 {
+		return c.Addresses
+	}
+	if c.Address != "" {
+		return []string{c.Address}
+	}
+	address, _ := joinHostPort(c.Host, c.Port)
+	return []string{address}
+}
+
+// joinHostPort builds an address from the legacy Host and Port fields,
+// keeping the path of Host and brackets of IPv6 literals. Port is added
+// only when Host has none, otherwise the address would carry two ports.
+func joinHostPort(host, port string) (string, error) {
+	if port == "" {
+		return host, nil
+	}
+
+	u, err := url.Parse(host)
+	if err != nil || u.Host == "" {
+		return fmt.Sprintf("%s:%s", host, port), nil
+	}
+	if u.Port() != "" {
+		return host, fmt.Errorf("invalid vault address %q: host already has port %s, port %s is not applied", host, u.Port(), port)
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), port)
+	return u.String(), nil
+}
+
+// validateHostPort checks the legacy Host and Port fields when they define the address.
+func (c ClientApi) validateHostPort() error {
+	if len(c.Addresses) > 0 || c.Address != "" {
+		return nil
+	}
+	_, err := joinHostPort(c.Host, c.Port)
+	return err
+}
+
+func (c ClientApi) endpointUrl(address, link string) string {
+	u := addressUrl(address)
+	u.Path = path.Join(u.Path, c.Version, link)
+
+	return u.String()
+}
+
+// addressUrl converts unix:///path/agent.sock into an http url dialed over the socket.
+func addressUrl(address string) *url.URL {
+	u, _ := url.Parse(address)
+	if u.Scheme == unixScheme {
+		return &url.URL{Scheme: "http", Host: unixSocketHost(u.Path)}
+	}
+	return u
+}
+
 func (c ClientApi) authUrl() string {
 	u, _ := url.Parse(c.baseUrl())
 	u.Path = path.Join(u.Path, c.AuthLink)
@@ -65,6 +131,29 @@ func getBaseClientApi() *ClientApi {
 	}
 }
 
+func getClientApi(api *ClientApi) *ClientApi {
+	if api == nil {
+		return getBaseClientApi()
+	}
+
+	return &ClientApi{
+		Address:    api.Address,
+		Host:       getHost(api.Host),
+		Port:       getHostPort(api.Host, api.Port),
+		Version:    getVersion(api.Version),
+		AuthLink:   getAuthLink(api.AuthLink),
+		UpdateLink: getUpdateLink(api.UpdateLink),
+		LookupLink: getLookupLink(api.LookupLink),
+
+		Addresses:      api.Addresses,
+		ProbeInterval:  api.ProbeInterval,
+		StandbyReads:   api.StandbyReads,
+		ReadAfterWrite: api.ReadAfterWrite,
+		Agent:          api.Agent,
+		Namespace:      api.Namespace,
+	}
+}
+
 func getHost(data string) string {
 	if data != "" {
 		return data
@@ -79,6 +168,17 @@ func getPort(data string) string {
 	return port
 }
 
+// getHostPort keeps the legacy default port, except for a Host that carries
+// its own port and has no explicit Port.
+func getHostPort(host, data string) string {
+	if data == "" {
+		if u, err := url.Parse(host); err == nil && u.Port() != "" {
+			return ""
+		}
+	}
+	return getPort(data)
+}
+
 func getVersion(data string) string {
 	if data != "" {
 		return data
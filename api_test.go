@@ -206,3 +206,118 @@ func TestBaseUrl(t *testing.T) {
 	actual := fmt.Sprintf("%s:%s/%s", api.Host, api.Port, api.Version)
 	assert.Equal(t, actual, api.baseUrl())
 }
+
+func TestEndpointUrl(t *testing.T) {
+	api := getBaseClientApi()
+
+	assert.Equal(t, "https://vault-1:8200/v1/auth/token/revoke-self", api.endpointUrl("https://vault-1:8200", revokeLink))
+	assert.Equal(t, "https://vault-1:8200/prefix/v1/sys/health", api.endpointUrl("https://vault-1:8200/prefix/", healthLink))
+}
+
+func TestAddresses(t *testing.T) {
+	api := getBaseClientApi()
+	assert.Equal(t, []string{fmt.Sprintf("%s:%s", host, port)}, api.addresses())
+
+	api.Addresses = []string{"https://vault-1:8200", "https://vault-2:8200"}
+	assert.Equal(t, api.Addresses, api.addresses())
+	assert.Equal(t, "https://vault-1:8200/v1", api.baseUrl())
+}
+
+func TestAddressUrl(t *testing.T) {
+	api := getBaseClientApi()
+	api.Addresses = []string{"unix:///run/vault/agent.sock"}
+
+	host := unixSocketHost("/run/vault/agent.sock")
+	assert.Equal(t, "http://"+host+"/v1", api.baseUrl())
+	assert.Equal(t, "http://"+host+"/v1/sys/health", api.endpointUrl(api.Addresses[0], healthLink))
+
+	socket, ok := unixSocketPath(host)
+	assert.True(t, ok)
+	assert.Equal(t, "/run/vault/agent.sock", socket)
+
+	_, ok = unixSocketPath("vault-1")
+	assert.False(t, ok)
+}
+
+func TestJoinHostPort(t *testing.T) {
+	testCases := []struct {
+		name   string
+		host   string
+		port   string
+		expect string
+		err    string
+	}{
+		{name: "hostPort", host: "https://vault", port: "8200", expect: "https://vault:8200"},
+		{name: "noPort", host: "https://vault", expect: "https://vault"},
+		{name: "hostWithPort", host: "https://vault:8200", expect: "https://vault:8200"},
+		{name: "pathPrefix", host: "https://proxy/vault/", port: "8443", expect: "https://proxy:8443/vault/"},
+		{name: "ipv6", host: "https://[::1]", port: "8200", expect: "https://[::1]:8200"},
+		{name: "ipv6WithPort", host: "https://[::1]:8200", expect: "https://[::1]:8200"},
+		{name: "noScheme", host: "vault", port: "8200", expect: "vault:8200"},
+		{
+			name: "twoPorts", host: "https://vault:8200", port: "8300", expect: "https://vault:8200",
+			err: `invalid vault address "https://vault:8200": host already has port 8200, port 8300 is not applied`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := joinHostPort(tc.host, tc.port)
+			assert.Equal(t, tc.expect, actual)
+			if tc.err == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestGetClientApiHostPort(t *testing.T) {
+	testCases := []struct {
+		name   string
+		api    *ClientApi
+		expect string
+		err    string
+	}{
+		{name: "defaultHost", api: &ClientApi{}, expect: fmt.Sprintf("%s:%s/v1", host, port)},
+		{name: "defaultHostPort", api: &ClientApi{Port: "8200"}, expect: host + ":8200/v1"},
+		{name: "hostWithPort", api: &ClientApi{Host: "https://vault:8200"}, expect: "https://vault:8200/v1"},
+		{name: "legacyDefaultPort", api: &ClientApi{Host: "https://vault.example.com"}, expect: "https://vault.example.com:8080/v1"},
+		{name: "addressSchemePort", api: &ClientApi{Address: "https://vault.example.com"}, expect: "https://vault.example.com/v1"},
+		{name: "addressesSchemePort", api: &ClientApi{Addresses: []string{"https://vault.example.com"}}, expect: "https://vault.example.com/v1"},
+		{name: "hostPort", api: &ClientApi{Host: "https://vault.example.com", Port: "8200"}, expect: "https://vault.example.com:8200/v1"},
+		{name: "ipv6WithPort", api: &ClientApi{Host: "https://[::1]:8200"}, expect: "https://[::1]:8200/v1"},
+		{
+			name: "twoPorts", api: &ClientApi{Host: "https://vault:8200", Port: "8300"}, expect: "https://vault:8200/v1",
+			err: `invalid vault address "https://vault:8200": host already has port 8200, port 8300 is not applied`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := getClientApi(tc.api)
+			assert.Equal(t, tc.expect, api.baseUrl())
+
+			err := api.validateHostPort()
+			if tc.err == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func TestAddressPrefixAndIPv6(t *testing.T) {
+	api := getClientApi(&ClientApi{Address: "https://proxy:8443/vault/"})
+	assert.Equal(t, []string{"https://proxy:8443/vault/"}, api.addresses())
+	assert.Equal(t, "https://proxy:8443/vault/v1", api.baseUrl())
+	assert.Equal(t, "https://proxy:8443/vault/v1/auth/approle/login", api.authUrl())
+
+	api = getClientApi(&ClientApi{Address: "https://[2001:db8::1]:8200"})
+	assert.Equal(t, "https://[2001:db8::1]:8200/v1/sys/health", api.endpointUrl(api.addresses()[0], healthLink))
+
+	api = getClientApi(&ClientApi{Host: "https://[2001:db8::1]", Port: "8200"})
+	assert.Equal(t, "https://[2001:db8::1]:8200/v1", api.baseUrl())
+}
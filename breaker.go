@@ -0,0 +1,199 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	baseBreakerFailureRate = 0.5
+	baseBreakerMinRequests = 5
+	baseBreakerWindow      = 10 * time.Second
+	baseBreakerCoolDown    = 30 * time.Second
+)
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+type BreakerOptions struct {
+	FailureRate float64       // доля ошибок в окне для размыкания, по умолчанию 0.5
+	MinRequests int           // минимум запросов в окне для оценки, по умолчанию 5
+	Window      time.Duration // окно подсчета ошибок, по умолчанию 10s
+	CoolDown    time.Duration // время в open до пробного запроса, по умолчанию 30s
+
+	OnStateChange func(host string, from, to CircuitState) // вызывается при смене состояния
+}
+
+// CircuitOpenError is returned without sending the request while the
+// circuit of the Vault host is open.
+type CircuitOpenError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("vault circuit breaker is open for %s, retry after %s", e.Host, e.RetryAfter)
+}
+
+type requestResult int
+
+const (
+	resultSuccess requestResult = iota
+	resultFailure
+	resultIgnored
+)
+
+type circuit struct {
+	state       CircuitState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openedAt    time.Time
+	probing     bool
+}
+
+type circuitBreaker struct {
+	mu       sync.Mutex
+	options  BreakerOptions
+	circuits map[string]*circuit
+
+	now func() time.Time
+}
+
+func newCircuitBreaker(options *BreakerOptions) *circuitBreaker {
+	if options == nil {
+		return nil
+	}
+
+	b := &circuitBreaker{
+		options:  *options,
+		circuits: make(map[string]*circuit),
+		now:      time.Now,
+	}
+	if b.options.FailureRate <= 0 {
+		b.options.FailureRate = baseBreakerFailureRate
+	}
+	if b.options.MinRequests <= 0 {
+		b.options.MinRequests = baseBreakerMinRequests
+	}
+	if b.options.Window <= 0 {
+		b.options.Window = baseBreakerWindow
+	}
+	if b.options.CoolDown <= 0 {
+		b.options.CoolDown = baseBreakerCoolDown
+	}
+	return b
+}
+
+// allow checks the circuit of host; done must be called with the request result.
+func (b *circuitBreaker) allow(host string) (func(requestResult), error) {
+	b.mu.Lock()
+	c, ok := b.circuits[host]
+	if !ok {
+		c = &circuit{windowStart: b.now()}
+		b.circuits[host] = c
+	}
+
+	from := c.state
+	if c.state == CircuitOpen {
+		wait := c.openedAt.Add(b.options.CoolDown).Sub(b.now())
+		if wait > 0 {
+			b.mu.Unlock()
+			return nil, &CircuitOpenError{Host: host, RetryAfter: wait}
+		}
+		c.state = CircuitHalfOpen
+	}
+	if c.state == CircuitHalfOpen {
+		if c.probing {
+			b.mu.Unlock()
+			return nil, &CircuitOpenError{Host: host}
+		}
+		c.probing = true
+	}
+	to := c.state
+	b.mu.Unlock()
+
+	b.changed(host, from, to)
+	return func(result requestResult) { b.record(host, c, result) }, nil
+}
+
+func (b *circuitBreaker) record(host string, c *circuit, result requestResult) {
+	b.mu.Lock()
+	from := c.state
+	now := b.now()
+
+	switch {
+	case result == resultIgnored:
+		c.probing = false
+	case c.state == CircuitHalfOpen:
+		c.probing = false
+		if result == resultFailure {
+			c.state, c.openedAt = CircuitOpen, now
+		} else {
+			c.state = CircuitClosed
+			c.windowStart, c.requests, c.failures = now, 0, 0
+		}
+	case c.state == CircuitClosed:
+		if now.Sub(c.windowStart) > b.options.Window {
+			c.windowStart, c.requests, c.failures = now, 0, 0
+		}
+		c.requests++
+		if result == resultFailure {
+			c.failures++
+		}
+		if c.requests >= b.options.MinRequests && float64(c.failures)/float64(c.requests) >= b.options.FailureRate {
+			c.state, c.openedAt = CircuitOpen, now
+		}
+	}
+	to := c.state
+	b.mu.Unlock()
+
+	b.changed(host, from, to)
+}
+
+func (b *circuitBreaker) changed(host string, from, to CircuitState) {
+	if from != to && b.options.OnStateChange != nil {
+		b.options.OnStateChange(host, from, to)
+	}
+}
+
+// breakerResult counts transport errors and 5xx responses as failures;
+// cancelled and throttled requests say nothing about Vault.
+func breakerResult(ctx context.Context, err error) requestResult {
+	switch {
+	case err == nil:
+		return resultSuccess
+	case ctx.Err() != nil || err == ErrThrottled:
+		return resultIgnored
+	case isEndpointFailure(err):
+		return resultFailure
+	}
+	return resultSuccess
+}
+
+func (b *circuitBreaker) state(host string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if c, ok := b.circuits[host]; ok {
+		return c.state
+	}
+	return CircuitClosed
+}
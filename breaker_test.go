@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBreakerResult(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		expect requestResult
+	}{
+		{name: "success", ctx: context.Background(), expect: resultSuccess},
+		{name: "transportError", ctx: context.Background(), err: errors.New("connection refused"), expect: resultFailure},
+		{name: "serverError", ctx: context.Background(), err: &ResponseError{StatusCode: 503}, expect: resultFailure},
+		{name: "clientError", ctx: context.Background(), err: &ResponseError{StatusCode: 403}, expect: resultSuccess},
+		{name: "cancelled", ctx: cancelled, err: context.Canceled, expect: resultIgnored},
+		{name: "throttled", ctx: context.Background(), err: ErrThrottled, expect: resultIgnored},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, breakerResult(tc.ctx, tc.err))
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Now()
+	var changes []string
+	b := newCircuitBreaker(&BreakerOptions{
+		MinRequests: 4,
+		CoolDown:    time.Minute,
+		OnStateChange: func(host string, from, to CircuitState) {
+			changes = append(changes, host+": "+from.String()+" -> "+to.String())
+		},
+	})
+	b.now = func() time.Time { return now }
+
+	for _, result := range []requestResult{resultSuccess, resultFailure, resultSuccess, resultFailure} {
+		done, err := b.allow("vault-1")
+		assert.Nil(t, err)
+		done(result)
+	}
+	assert.Equal(t, CircuitOpen, b.state("vault-1"))
+	assert.Equal(t, CircuitClosed, b.state("vault-2"))
+
+	_, err := b.allow("vault-1")
+	assert.Equal(t, &CircuitOpenError{Host: "vault-1", RetryAfter: time.Minute}, err)
+
+	now = now.Add(time.Minute)
+	probe, err := b.allow("vault-1")
+	assert.Nil(t, err)
+	_, err = b.allow("vault-1")
+	assert.Error(t, err, "")
+	probe(resultFailure)
+	assert.Equal(t, CircuitOpen, b.state("vault-1"))
+
+	now = now.Add(time.Minute)
+	probe, _ = b.allow("vault-1")
+	probe(resultIgnored)
+	probe, _ = b.allow("vault-1")
+	probe(resultSuccess)
+	assert.Equal(t, CircuitClosed, b.state("vault-1"))
+
+	assert.Equal(t, []string{
+		"vault-1: closed -> open",
+		"vault-1: open -> half-open",
+		"vault-1: half-open -> open",
+		"vault-1: open -> half-open",
+		"vault-1: half-open -> closed",
+	}, changes)
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	now := time.Now()
+	b := newCircuitBreaker(&BreakerOptions{MinRequests: 2, Window: time.Second})
+	b.now = func() time.Time { return now }
+
+	done, _ := b.allow("vault-1")
+	done(resultFailure)
+	now = now.Add(2 * time.Second)
+	done, _ = b.allow("vault-1")
+	done(resultFailure)
+	assert.Equal(t, CircuitClosed, b.state("vault-1"))
+
+	done, _ = b.allow("vault-1")
+	done(resultFailure)
+	assert.Equal(t, CircuitOpen, b.state("vault-1"))
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	client.actions.breaker = newCircuitBreaker(&BreakerOptions{MinRequests: 2})
+
+	for i := 0; i < 3; i++ {
+		_, err := client.Get("secret/path")
+		assert.Error(t, err, "")
+	}
+
+	_, err := client.Get("secret/path")
+	_, ok := err.(*CircuitOpenError)
+	assert.True(t, ok)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, CircuitOpen, client.CircuitState(server.URL))
+}
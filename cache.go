@@ -0,0 +1,142 @@
+package vault
+
+import (
+	"container/list"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type CacheOptions struct {
+	TTL          time.Duration // время жизни записи
+	MaxEntries   int           // максимальное число записей, 0 - без ограничений
+	StaleIfError bool          // отдавать последнее значение, если Vault недоступен
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	StaleHits uint64
+	Evictions uint64
+}
+
+type cacheEntry struct {
+	key      string
+	value    interface{}
+	expireAt time.Time
+}
+
+type secretCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	max     int
+	stale   bool
+	entries map[string]*list.Element
+	lru     *list.List
+
+	hits      uint64
+	misses    uint64
+	staleHits uint64
+	evictions uint64
+
+	now func() time.Time
+}
+
+// isUnavailable reports whether a stale value may replace the error: Vault is not
+// reachable, fails or throttles. 403 and 404 are answers and are never masked by the cache.
+func isUnavailable(err error) bool {
+	switch e := err.(type) {
+	case *ResponseError:
+		return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests
+	case *CircuitOpenError, *url.Error, net.Error:
+		return true
+	}
+	return err == ErrThrottled
+}
+
+func newSecretCache(options *CacheOptions) *secretCache {
+	if options == nil {
+		return nil
+	}
+
+	return &secretCache{
+		ttl:     options.TTL,
+		max:     options.MaxEntries,
+		stale:   options.StaleIfError,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		now:     time.Now,
+	}
+}
+
+func (s *secretCache) get(key string) (interface{}, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok || s.now().After(element.Value.(*cacheEntry).expireAt) {
+		atomic.AddUint64(&s.misses, 1)
+		return nil, false
+	}
+
+	s.lru.MoveToFront(element)
+	atomic.AddUint64(&s.hits, 1)
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (s *secretCache) getStale(key string) (interface{}, bool) {
+	if !s.stale {
+		return nil, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	element, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	atomic.AddUint64(&s.staleHits, 1)
+	return element.Value.(*cacheEntry).value, true
+}
+
+func (s *secretCache) set(key string, value interface{}, leaseDuration time.Duration) {
+	ttl := s.ttl
+	if leaseDuration > 0 && (ttl <= 0 || leaseDuration < ttl) {
+		ttl = leaseDuration
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := &cacheEntry{key: key, value: value, expireAt: s.now().Add(ttl)}
+	if element, ok := s.entries[key]; ok {
+		element.Value = entry
+		s.lru.MoveToFront(element)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(entry)
+
+	for s.max > 0 && s.lru.Len() > s.max {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*cacheEntry).key)
+		atomic.AddUint64(&s.evictions, 1)
+	}
+}
+
+func (s *secretCache) stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&s.hits),
+		Misses:    atomic.LoadUint64(&s.misses),
+		StaleHits: atomic.LoadUint64(&s.staleHits),
+		Evictions: atomic.LoadUint64(&s.evictions),
+	}
+}
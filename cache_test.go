@@ -0,0 +1,122 @@
+package vault
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestSecretCache(options *CacheOptions, now *time.Time) *secretCache {
+	cache := newSecretCache(options)
+	cache.now = func() time.Time { return *now }
+	return cache
+}
+
+func TestNewSecretCacheDisabled(t *testing.T) {
+	assert.Nil(t, newSecretCache(nil))
+}
+
+func TestSecretCacheTTL(t *testing.T) {
+	now := time.Now()
+	cache := newTestSecretCache(&CacheOptions{TTL: time.Minute}, &now)
+
+	cache.set("key", "value", 0)
+	data, ok := cache.get("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", data)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get("key")
+	assert.False(t, ok)
+
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 1}, cache.stats())
+}
+
+func TestSecretCacheLeaseDuration(t *testing.T) {
+	testCases := []struct {
+		name   string
+		ttl    time.Duration
+		lease  time.Duration
+		after  time.Duration
+		expect bool
+	}{
+		{name: "leaseShorterThanTTL", ttl: time.Hour, lease: time.Minute, after: 2 * time.Minute, expect: false},
+		{name: "leaseLongerThanTTL", ttl: time.Minute, lease: time.Hour, after: 2 * time.Minute, expect: false},
+		{name: "leaseWithoutTTL", ttl: 0, lease: time.Hour, after: 2 * time.Minute, expect: true},
+		{name: "noLeaseNoTTL", ttl: 0, lease: 0, after: 0, expect: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			cache := newTestSecretCache(&CacheOptions{TTL: tc.ttl}, &now)
+
+			cache.set("key", "value", tc.lease)
+			now = now.Add(tc.after)
+
+			_, ok := cache.get("key")
+			assert.Equal(t, tc.expect, ok)
+		})
+	}
+}
+
+func TestSecretCacheEviction(t *testing.T) {
+	now := time.Now()
+	cache := newTestSecretCache(&CacheOptions{TTL: time.Minute, MaxEntries: 2}, &now)
+
+	cache.set("a", 1, 0)
+	cache.set("b", 2, 0)
+	_, _ = cache.get("a")
+	cache.set("c", 3, 0)
+
+	_, ok := cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), cache.stats().Evictions)
+}
+
+func TestSecretCacheStale(t *testing.T) {
+	now := time.Now()
+	cache := newTestSecretCache(&CacheOptions{TTL: time.Minute, StaleIfError: true}, &now)
+
+	cache.set("key", "value", 0)
+	now = now.Add(time.Hour)
+
+	data, ok := cache.getStale("key")
+	assert.True(t, ok)
+	assert.Equal(t, "value", data)
+	assert.Equal(t, uint64(1), cache.stats().StaleHits)
+
+	cache.stale = false
+	_, ok = cache.getStale("key")
+	assert.False(t, ok)
+}
+
+func TestIsUnavailable(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{name: "serverError", err: &ResponseError{StatusCode: http.StatusServiceUnavailable}, expect: true},
+		{name: "rateLimited", err: &ResponseError{StatusCode: http.StatusTooManyRequests}, expect: true},
+		{name: "forbidden", err: &ResponseError{StatusCode: http.StatusForbidden}},
+		{name: "notFound", err: &ResponseError{StatusCode: http.StatusNotFound}},
+		{name: "transport", err: &url.Error{Op: "Get", URL: "https://vault", Err: errors.New("connection refused")}, expect: true},
+		{name: "circuitOpen", err: &CircuitOpenError{Host: "vault"}, expect: true},
+		{name: "throttled", err: ErrThrottled, expect: true},
+		{name: "decode", err: errors.New("invalid character")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, isUnavailable(tc.err))
+		})
+	}
+}
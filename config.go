@@ -0,0 +1,507 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const envProfile = "VAULT_PROFILE"
+
+const (
+	AuthAppRole = "approle"
+	AuthToken   = "token"
+	AuthAgent   = "agent"
+
+	StoreFile      = "file"
+	StoreEncrypted = "encrypted"
+	StoreMemory    = "memory"
+	StoreNone      = "none"
+)
+
+type Config struct {
+	DefaultProfile string
+	Profiles       map[string]*Profile
+}
+
+type Profile struct {
+	Addresses  []string // address: один адрес или список узлов
+	Namespace  string
+	CACert     string
+	CAPath     string
+	ClientCert string
+	ClientKey  string
+	SkipVerify bool
+
+	Auth       ProfileAuth
+	TokenStore ProfileTokenStore
+	Retry      ProfileRetry
+}
+
+type ProfileAuth struct {
+	Method       string // approle, token или agent
+	Mount        string // путь метода авторизации, по умолчанию approle
+	RoleId       string
+	SecretId     string
+	SecretIdFile string
+	Token        string
+}
+
+type ProfileTokenStore struct {
+	Type    string // file, encrypted, memory или none
+	Path    string
+	KeyFile string // ключ для encrypted, по умолчанию MachineSecret()
+}
+
+type ProfileRetry struct {
+	MaxRetries int
+	Wait       time.Duration
+}
+
+// ConfigError points at the key of the config file that failed validation.
+type ConfigError struct {
+	Key     string
+	Message string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// LoadConfig reads a JSON, YAML or TOML config file, the format is
+// chosen by the file extension.
+func LoadConfig(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	case ".toml":
+		var table map[string]interface{}
+		_, err = toml.Decode(string(data), &table)
+		raw = table
+	default:
+		return nil, fmt.Errorf("unsupported config format: %s", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config %s: %v", file, err)
+	}
+
+	return decodeConfig(normalizeConfigValue(raw))
+}
+
+// NewClientFromConfig creates a client from a profile of the config file.
+// An empty profile falls back to VAULT_PROFILE, then to default_profile
+// or the only profile of the file.
+func NewClientFromConfig(file, profile string) (*Client, error) {
+	config, err := LoadConfig(file)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewClient(profile)
+}
+
+func (c Config) NewClient(profile string) (*Client, error) {
+	name, err := c.profileName(profile)
+	if err != nil {
+		return nil, err
+	}
+	return c.Profiles[name].newClient("profiles." + name)
+}
+
+func (c Config) profileName(profile string) (string, error) {
+	if profile == "" {
+		profile = os.Getenv(envProfile)
+	}
+	if profile == "" {
+		profile = c.DefaultProfile
+	}
+	if profile == "" && len(c.Profiles) == 1 {
+		for name := range c.Profiles {
+			profile = name
+		}
+	}
+
+	if profile == "" {
+		return "", &ConfigError{Key: "default_profile", Message: "profile is not selected"}
+	}
+	if _, ok := c.Profiles[profile]; !ok {
+		return "", &ConfigError{Key: "profiles." + profile, Message: "profile not found"}
+	}
+	return profile, nil
+}
+
+func (p Profile) newClient(key string) (*Client, error) {
+	options := &ClientOptions{
+		CertFilePath: p.CACert,
+		TLS: TLSOptions{
+			CAPath:             p.CAPath,
+			ClientCertFile:     p.ClientCert,
+			ClientKeyFile:      p.ClientKey,
+			InsecureSkipVerify: p.SkipVerify,
+			UseSystemRoots:     p.CACert == "" && p.CAPath == "",
+		},
+		MaxRetries: p.Retry.MaxRetries,
+		RetryWait:  p.Retry.Wait,
+	}
+	api := &ClientApi{
+		Addresses: p.Addresses,
+		Namespace: p.Namespace,
+		Agent:     p.Auth.Method == AuthAgent,
+	}
+
+	var roleId, secretId string
+	switch p.Auth.Method {
+	case AuthAppRole:
+		roleId, secretId = p.Auth.RoleId, p.Auth.SecretId
+		if p.Auth.SecretIdFile != "" {
+			data, err := ioutil.ReadFile(p.Auth.SecretIdFile)
+			if err != nil {
+				return nil, &ConfigError{Key: key + ".auth.secret_id_file", Message: err.Error()}
+			}
+			secretId = strings.TrimSpace(string(data))
+		}
+		if p.Auth.Mount != "" {
+			api.AuthLink = path.Join("auth", p.Auth.Mount, "login")
+		}
+	case AuthToken:
+		options.Token = p.Auth.Token
+	}
+
+	switch p.TokenStore.Type {
+	case StoreFile:
+		options.TokenFilePath = p.TokenStore.Path
+	case StoreMemory:
+		options.TokenStore = NewMemoryTokenStore()
+	case StoreNone:
+		options.TokenStore = NoopTokenStore{}
+	case StoreEncrypted:
+		secret, err := MachineSecret()
+		if p.TokenStore.KeyFile != "" {
+			secret, err = KeyFileSecret(p.TokenStore.KeyFile)
+		}
+		if err != nil {
+			return nil, &ConfigError{Key: key + ".token_store.key_file", Message: err.Error()}
+		}
+		store, err := NewEncryptedTokenStore(p.TokenStore.Path, secret)
+		if err != nil {
+			return nil, &ConfigError{Key: key + ".token_store", Message: err.Error()}
+		}
+		options.TokenStore = store
+	}
+
+	return NewCustomClient(roleId, secretId, options, api)
+}
+
+func decodeConfig(raw interface{}) (*Config, error) {
+	d := &configDecoder{}
+	root := d.object("", raw)
+
+	config := &Config{Profiles: map[string]*Profile{}}
+	config.DefaultProfile = root.string("default_profile")
+
+	profiles := root.object("profiles")
+	for _, name := range profiles.keys() {
+		config.Profiles[name] = decodeProfile(profiles.object(name))
+	}
+	root.done()
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if len(config.Profiles) == 0 {
+		return nil, &ConfigError{Key: "profiles", Message: "no profiles defined"}
+	}
+	if config.DefaultProfile != "" && config.Profiles[config.DefaultProfile] == nil {
+		return nil, &ConfigError{Key: "default_profile", Message: "profile " + config.DefaultProfile + " not found"}
+	}
+	return config, nil
+}
+
+func decodeProfile(o *configObject) *Profile {
+	p := &Profile{
+		Addresses:  o.strings("address"),
+		Namespace:  o.string("namespace"),
+		CACert:     o.string("ca_cert"),
+		CAPath:     o.string("ca_path"),
+		ClientCert: o.string("client_cert"),
+		ClientKey:  o.string("client_key"),
+		SkipVerify: o.bool("skip_verify"),
+	}
+
+	if len(p.Addresses) == 0 {
+		o.fail("address", "required")
+	}
+	for _, address := range p.Addresses {
+		if err := validateAddress(address); err != nil {
+			o.fail("address", err.Error())
+		}
+	}
+	if (p.ClientCert == "") != (p.ClientKey == "") {
+		o.fail("client_key", "client_cert and client_key must be set together")
+	}
+
+	auth := o.object("auth")
+	p.Auth = ProfileAuth{
+		Method:       auth.string("method"),
+		Mount:        auth.string("mount"),
+		RoleId:       auth.string("role_id"),
+		SecretId:     auth.string("secret_id"),
+		SecretIdFile: auth.string("secret_id_file"),
+		Token:        auth.string("token"),
+	}
+	if p.Auth.Method == "" {
+		p.Auth.Method = AuthAppRole
+	}
+	switch p.Auth.Method {
+	case AuthAppRole:
+		if p.Auth.RoleId == "" {
+			auth.fail("role_id", "required for approle auth")
+		}
+		if p.Auth.SecretId == "" && p.Auth.SecretIdFile == "" {
+			auth.fail("secret_id", "secret_id or secret_id_file required for approle auth")
+		}
+	case AuthToken:
+		if p.Auth.Token == "" {
+			auth.fail("token", "required for token auth")
+		}
+	case AuthAgent:
+	default:
+		auth.fail("method", "unknown auth method "+p.Auth.Method)
+	}
+	auth.done()
+
+	store := o.object("token_store")
+	p.TokenStore = ProfileTokenStore{
+		Type:    store.string("type"),
+		Path:    store.string("path"),
+		KeyFile: store.string("key_file"),
+	}
+	if p.TokenStore.Type == "" {
+		p.TokenStore.Type = StoreFile
+	}
+	switch p.TokenStore.Type {
+	case StoreFile, StoreMemory, StoreNone:
+	case StoreEncrypted:
+		if p.TokenStore.Path == "" {
+			store.fail("path", "required for encrypted token store")
+		}
+	default:
+		store.fail("type", "unknown token store "+p.TokenStore.Type)
+	}
+	store.done()
+
+	retry := o.object("retry")
+	p.Retry = ProfileRetry{
+		MaxRetries: retry.int("max_retries"),
+		Wait:       retry.duration("wait"),
+	}
+	if p.Retry.MaxRetries < 0 {
+		retry.fail("max_retries", "must not be negative")
+	}
+	retry.done()
+
+	o.done()
+	return p
+}
+
+// configDecoder keeps the first error, so decoding reads like plain
+// field access and the error is checked once at the end.
+type configDecoder struct {
+	err error
+}
+
+type configObject struct {
+	decoder *configDecoder
+	path    string
+	values  map[string]interface{}
+	used    map[string]bool
+}
+
+func (d *configDecoder) object(key string, value interface{}) *configObject {
+	o := &configObject{decoder: d, path: key, used: map[string]bool{}}
+	if value == nil {
+		return o
+	}
+
+	values, ok := value.(map[string]interface{})
+	if !ok {
+		d.fail(key, "must be an object")
+		return o
+	}
+	o.values = values
+	return o
+}
+
+func (d *configDecoder) fail(key, message string) {
+	if d.err == nil {
+		d.err = &ConfigError{Key: key, Message: message}
+	}
+}
+
+func (o *configObject) key(key string) string {
+	if o.path == "" {
+		return key
+	}
+	return o.path + "." + key
+}
+
+func (o *configObject) fail(key, message string) {
+	o.decoder.fail(o.key(key), message)
+}
+
+func (o *configObject) get(key string) interface{} {
+	o.used[key] = true
+	return o.values[key]
+}
+
+func (o *configObject) keys() []string {
+	keys := make([]string, 0, len(o.values))
+	for key := range o.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (o *configObject) object(key string) *configObject {
+	return o.decoder.object(o.key(key), o.get(key))
+}
+
+func (o *configObject) string(key string) string {
+	switch value := o.get(key).(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	}
+	o.fail(key, "must be a string")
+	return ""
+}
+
+func (o *configObject) strings(key string) []string {
+	switch value := o.get(key).(type) {
+	case nil:
+		return nil
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			s, ok := item.(string)
+			if !ok {
+				o.fail(key, "must be a string or a list of strings")
+				return nil
+			}
+			result = append(result, s)
+		}
+		return result
+	}
+	o.fail(key, "must be a string or a list of strings")
+	return nil
+}
+
+func (o *configObject) bool(key string) bool {
+	switch value := o.get(key).(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	}
+	o.fail(key, "must be a boolean")
+	return false
+}
+
+func (o *configObject) int(key string) int {
+	switch value := o.get(key).(type) {
+	case nil:
+		return 0
+	case int:
+		return value
+	case int64:
+		return int(value)
+	case float64:
+		if value == math.Trunc(value) {
+			return int(value)
+		}
+	}
+	o.fail(key, "must be an integer")
+	return 0
+}
+
+// duration accepts Go duration strings ("500ms") and numbers of seconds.
+func (o *configObject) duration(key string) time.Duration {
+	switch value := o.get(key).(type) {
+	case nil:
+		return 0
+	case string:
+		d, err := time.ParseDuration(value)
+		if err == nil {
+			return d
+		}
+	case int:
+		return time.Duration(value) * time.Second
+	case int64:
+		return time.Duration(value) * time.Second
+	case float64:
+		return time.Duration(value * float64(time.Second))
+	}
+	o.fail(key, "must be a duration")
+	return 0
+}
+
+// done reports the first key that was not read as unknown.
+func (o *configObject) done() {
+	for _, key := range o.keys() {
+		if !o.used[key] {
+			o.fail(key, "unknown key")
+			return
+		}
+	}
+}
+
+// normalizeConfigValue converts YAML maps to map[string]interface{}.
+func normalizeConfigValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalizeConfigValue(item)
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[key] = normalizeConfigValue(item)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeConfigValue(item)
+		}
+		return result
+	case []map[string]interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = normalizeConfigValue(item)
+		}
+		return result
+	}
+	return value
+}
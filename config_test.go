@@ -0,0 +1,276 @@
+package vault
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const configTestJSON = `{
+  "default_profile": "dev",
+  "profiles": {
+    "dev": {
+      "address": "https://vault-dev:8200",
+      "auth": {"method": "token", "token": "dev_token"},
+      "token_store": {"type": "memory"}
+    },
+    "prod": {
+      "address": ["https://vault-1:8200", "https://vault-2:8200"],
+      "namespace": "team",
+      "ca_cert": "/etc/ssl/vault/ca.pem",
+      "auth": {"role_id": "role", "secret_id_file": "/etc/vault/secret_id", "mount": "approle-prod"},
+      "token_store": {"type": "encrypted", "path": "/var/lib/app/.token"},
+      "retry": {"max_retries": 3, "wait": "500ms"}
+    }
+  }
+}`
+
+const configTestYAML = `
+default_profile: dev
+profiles:
+  dev:
+    address: https://vault-dev:8200
+    auth:
+      method: token
+      token: dev_token
+    token_store:
+      type: memory
+  prod:
+    address:
+      - https://vault-1:8200
+      - https://vault-2:8200
+    namespace: team
+    ca_cert: /etc/ssl/vault/ca.pem
+    auth:
+      role_id: role
+      secret_id_file: /etc/vault/secret_id
+      mount: approle-prod
+    token_store:
+      type: encrypted
+      path: /var/lib/app/.token
+    retry:
+      max_retries: 3
+      wait: 500ms
+`
+
+const configTestTOML = `
+default_profile = "dev"
+
+[profiles.dev]
+address = "https://vault-dev:8200"
+auth = { method = "token", token = "dev_token" }
+token_store = { type = "memory" }
+
+[profiles.prod]
+address = ["https://vault-1:8200", "https://vault-2:8200"]
+namespace = "team"
+ca_cert = "/etc/ssl/vault/ca.pem"
+
+[profiles.prod.auth]
+role_id = "role"
+secret_id_file = "/etc/vault/secret_id"
+mount = "approle-prod"
+
+[profiles.prod.token_store]
+type = "encrypted"
+path = "/var/lib/app/.token"
+
+[profiles.prod.retry]
+max_retries = 3
+wait = "500ms"
+`
+
+func TestLoadConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	expect := &Config{
+		DefaultProfile: "dev",
+		Profiles: map[string]*Profile{
+			"dev": {
+				Addresses:  []string{"https://vault-dev:8200"},
+				Auth:       ProfileAuth{Method: AuthToken, Token: "dev_token"},
+				TokenStore: ProfileTokenStore{Type: StoreMemory},
+			},
+			"prod": {
+				Addresses:  []string{"https://vault-1:8200", "https://vault-2:8200"},
+				Namespace:  "team",
+				CACert:     "/etc/ssl/vault/ca.pem",
+				Auth:       ProfileAuth{Method: AuthAppRole, RoleId: "role", SecretIdFile: "/etc/vault/secret_id", Mount: "approle-prod"},
+				TokenStore: ProfileTokenStore{Type: StoreEncrypted, Path: "/var/lib/app/.token"},
+				Retry:      ProfileRetry{MaxRetries: 3, Wait: 500 * time.Millisecond},
+			},
+		},
+	}
+
+	testCases := []struct {
+		name string
+		file string
+		data string
+	}{
+		{name: "json", file: "vault.json", data: configTestJSON},
+		{name: "yaml", file: "vault.yaml", data: configTestYAML},
+		{name: "toml", file: "vault.toml", data: configTestTOML},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config, err := LoadConfig(writeTestFile(t, dir, tc.file, []byte(tc.data)))
+			assert.Nil(t, err)
+			assert.Equal(t, expect, config)
+		})
+	}
+
+	_, err := LoadConfig(writeTestFile(t, dir, "vault.ini", []byte(configTestJSON)))
+	assert.Error(t, err, "")
+	_, err = LoadConfig(writeTestFile(t, dir, "broken.json", []byte("{")))
+	assert.Error(t, err, "")
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name   string
+		data   string
+		expect *ConfigError
+	}{
+		{
+			name:   "noProfiles",
+			data:   `default_profile: dev`,
+			expect: &ConfigError{Key: "profiles", Message: "no profiles defined"},
+		},
+		{
+			name:   "unknownRootKey",
+			data:   "profile:\n  dev: {}",
+			expect: &ConfigError{Key: "profile", Message: "unknown key"},
+		},
+		{
+			name:   "unknownProfileKey",
+			data:   "profiles:\n  dev:\n    address: https://vault:8200\n    adress: https://vault:8200\n    auth: {method: agent}",
+			expect: &ConfigError{Key: "profiles.dev.adress", Message: "unknown key"},
+		},
+		{
+			name:   "addressRequired",
+			data:   "profiles:\n  dev:\n    auth: {method: agent}",
+			expect: &ConfigError{Key: "profiles.dev.address", Message: "required"},
+		},
+		{
+			name:   "addressInvalid",
+			data:   "profiles:\n  dev:\n    address: vault:8200\n    auth: {method: agent}",
+			expect: &ConfigError{Key: "profiles.dev.address", Message: `invalid vault address "vault:8200": unsupported scheme "vault", expected http, https or unix`},
+		},
+		{
+			name:   "addressType",
+			data:   "profiles:\n  dev:\n    address: [1]\n    auth: {method: agent}",
+			expect: &ConfigError{Key: "profiles.dev.address", Message: "must be a string or a list of strings"},
+		},
+		{
+			name:   "authMethod",
+			data:   "profiles:\n  dev:\n    address: https://vault:8200\n    auth: {method: ldap}",
+			expect: &ConfigError{Key: "profiles.dev.auth.method", Message: "unknown auth method ldap"},
+		},
+		{
+			name:   "approleRoleId",
+			data:   "profiles:\n  dev:\n    address: https://vault:8200\n    auth: {secret_id: secret}",
+			expect: &ConfigError{Key: "profiles.dev.auth.role_id", Message: "required for approle auth"},
+		},
+		{
+			name:   "tokenStoreType",
+			data:   "profiles:\n  dev:\n    address: https://vault:8200\n    auth: {method: agent}\n    token_store: {type: redis}",
+			expect: &ConfigError{Key: "profiles.dev.token_store.type", Message: "unknown token store redis"},
+		},
+		{
+			name:   "retryWait",
+			data:   "profiles:\n  dev:\n    address: https://vault:8200\n    auth: {method: agent}\n    retry: {wait: soon}",
+			expect: &ConfigError{Key: "profiles.dev.retry.wait", Message: "must be a duration"},
+		},
+		{
+			name:   "skipVerify",
+			data:   "profiles:\n  dev:\n    address: https://vault:8200\n    auth: {method: agent}\n    skip_verify: \"yes\"",
+			expect: &ConfigError{Key: "profiles.dev.skip_verify", Message: "must be a boolean"},
+		},
+		{
+			name:   "defaultProfile",
+			data:   "default_profile: prod\nprofiles:\n  dev:\n    address: https://vault:8200\n    auth: {method: agent}",
+			expect: &ConfigError{Key: "default_profile", Message: "profile prod not found"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := LoadConfig(writeTestFile(t, dir, tc.name+".yaml", []byte(tc.data)))
+			assert.Equal(t, tc.expect, err)
+		})
+	}
+}
+
+func TestConfigProfileName(t *testing.T) {
+	setTestEnv(t, nil)
+	_ = os.Unsetenv(envProfile)
+	defer os.Unsetenv(envProfile)
+
+	single := Config{Profiles: map[string]*Profile{"dev": {}}}
+	multi := Config{Profiles: map[string]*Profile{"dev": {}, "prod": {}}}
+
+	name, err := single.profileName("")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev", name)
+
+	_, err = multi.profileName("")
+	assert.Equal(t, &ConfigError{Key: "default_profile", Message: "profile is not selected"}, err)
+
+	_, err = multi.profileName("stage")
+	assert.Equal(t, &ConfigError{Key: "profiles.stage", Message: "profile not found"}, err)
+
+	multi.DefaultProfile = "dev"
+	_ = os.Setenv(envProfile, "prod")
+	name, _ = multi.profileName("")
+	assert.Equal(t, "prod", name)
+	name, _ = multi.profileName("dev")
+	assert.Equal(t, "dev", name)
+}
+
+func TestNewClientFromConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/secret/path", req.URL.Path)
+		assert.Equal(t, "dev_token", req.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "team", req.Header.Get("X-Vault-Namespace"))
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+	_ = os.Unsetenv(envProfile)
+
+	file := writeTestFile(t, dir, "vault.yaml", []byte(`
+profiles:
+  dev:
+    address: `+server.URL+`
+    namespace: team
+    auth: {method: token, token: dev_token}
+    token_store: {type: none}
+  prod:
+    address: https://vault:8200
+    auth: {role_id: role, secret_id_file: `+filepath.Join(dir, "not_exist")+`}
+`))
+
+	client, err := NewClientFromConfig(file, "dev")
+	assert.Nil(t, err)
+	defer client.Close()
+
+	_, err = client.Get("secret/path")
+	assert.Nil(t, err)
+
+	_, err = NewClientFromConfig(file, "prod")
+	configErr, ok := err.(*ConfigError)
+	assert.True(t, ok)
+	assert.Equal(t, "profiles.prod.auth.secret_id_file", configErr.Key)
+}
@@ -0,0 +1,376 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	baseProbeInterval = 30 * time.Second
+	healthLink        = "sys/health"
+
+	statusPerfStandby = 473
+
+	baseRetryWait = 250 * time.Millisecond
+	maxRetryWait  = 5 * time.Second
+)
+
+type nodeRole int
+
+const (
+	roleUnknown nodeRole = iota
+	roleActive
+	roleStandby
+	rolePerfStandby
+)
+
+type endpoint struct {
+	address  string
+	role     nodeRole
+	failedAt time.Time
+}
+
+type endpointPool struct {
+	mu        sync.RWMutex
+	endpoints []*endpoint
+	index     string
+
+	probeOnce sync.Once
+	stop      chan struct{}
+	stopOnce  sync.Once
+}
+
+func newEndpointPool(addresses []string) (*endpointPool, error) {
+	pool := &endpointPool{stop: make(chan struct{})}
+	if err := pool.set(addresses); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+func (p *endpointPool) set(addresses []string) error {
+	if len(addresses) == 0 {
+		return errors.New("vault addresses list is empty")
+	}
+	for _, address := range addresses {
+		if err := validateAddress(address); err != nil {
+			return err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	known := make(map[string]*endpoint, len(p.endpoints))
+	for _, e := range p.endpoints {
+		known[e.address] = e
+	}
+
+	endpoints := make([]*endpoint, 0, len(addresses))
+	for _, address := range addresses {
+		if e, ok := known[address]; ok {
+			endpoints = append(endpoints, e)
+			continue
+		}
+		endpoints = append(endpoints, &endpoint{address: address})
+	}
+	p.endpoints = endpoints
+	return nil
+}
+
+// candidates returns endpoints in the order they should be tried.
+// Writes prefer the active node, reads with standbyReads prefer
+// performance standbys; failed nodes are always tried last.
+func (p *endpointPool) candidates(standbyReads bool) []*endpoint {
+	order := []nodeRole{roleActive, roleUnknown, rolePerfStandby, roleStandby}
+	if standbyReads {
+		order = []nodeRole{rolePerfStandby, roleActive, roleUnknown, roleStandby}
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]*endpoint, 0, len(p.endpoints))
+	for _, role := range order {
+		for _, e := range p.endpoints {
+			if e.failedAt.IsZero() && e.role == role {
+				result = append(result, e)
+			}
+		}
+	}
+	for _, e := range p.endpoints {
+		if !e.failedAt.IsZero() {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func (p *endpointPool) addresses() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	result := make([]string, 0, len(p.endpoints))
+	for _, e := range p.endpoints {
+		result = append(result, e.address)
+	}
+	return result
+}
+
+func (p *endpointPool) markFailed(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if e.failedAt.IsZero() {
+		e.failedAt = time.Now()
+	}
+	e.role = roleUnknown
+}
+
+func (p *endpointPool) markAlive(e *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failedAt = time.Time{}
+}
+
+func (p *endpointPool) markHealthy(e *endpoint, role nodeRole) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e.failedAt = time.Time{}
+	e.role = role
+}
+
+func (p *endpointPool) role(e *endpoint) nodeRole {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return e.role
+}
+
+func (p *endpointPool) lastIndex() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.index
+}
+
+func (p *endpointPool) setIndex(index string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.index = index
+}
+
+func (p *endpointPool) close() {
+	p.stopOnce.Do(func() { close(p.stop) })
+}
+
+func (p *endpointPool) startProbe(interval time.Duration, probe func(e *endpoint)) {
+	p.probeOnce.Do(func() { go p.probeLoop(interval, probe) })
+}
+
+func (p *endpointPool) probeLoop(interval time.Duration, probe func(e *endpoint)) {
+	if interval <= 0 {
+		interval = baseProbeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for _, e := range p.candidates(false) {
+			probe(e)
+		}
+
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c Client) probe(e *endpoint) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := c.actions.request(ctx, "GET", c.api.endpointUrl(e.address, healthLink), "", nil)
+	if err == nil {
+		c.endpoints.markHealthy(e, roleActive)
+		return
+	}
+
+	if responseErr, ok := err.(*ResponseError); ok {
+		switch responseErr.StatusCode {
+		case http.StatusTooManyRequests:
+			c.endpoints.markHealthy(e, roleStandby)
+			return
+		case statusPerfStandby:
+			c.endpoints.markHealthy(e, rolePerfStandby)
+			return
+		}
+	}
+	c.endpoints.markFailed(e)
+}
+
+func (c Client) request(ctx context.Context, method, link, token string, data []byte) ([]byte, error) {
+	response, err := c.requestEndpoints(ctx, method, link, token, data)
+
+	wait := c.options.RetryWait
+	if wait <= 0 {
+		wait = baseRetryWait
+	}
+	for attempt := 0; err != nil && attempt < c.options.MaxRetries && isRetryable(err); attempt++ {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+
+		wait *= 2
+		if wait > maxRetryWait {
+			wait = maxRetryWait
+		}
+		response, err = c.requestEndpoints(ctx, method, link, token, data)
+	}
+	return response, err
+}
+
+func (c Client) requestEndpoints(ctx context.Context, method, link, token string, data []byte) ([]byte, error) {
+	read := method == "GET" || method == "LIST"
+
+	header := http.Header{}
+	if c.api.Namespace != "" {
+		header.Set("X-Vault-Namespace", c.api.Namespace)
+	}
+	if c.api.Agent {
+		header.Set("X-Vault-Request", "true")
+	}
+	if c.api.ReadAfterWrite {
+		if index := c.endpoints.lastIndex(); index != "" {
+			header.Set("X-Vault-Index", index)
+			header.Set("X-Vault-Inconsistent", "forward-active-node")
+		}
+	}
+
+	var lastErr error
+	for _, e := range c.endpoints.candidates(read && c.api.StandbyReads) {
+		response, responseHeader, err := c.actions.send(ctx, method, c.api.endpointUrl(e.address, link), token, data, header)
+		if err != nil && (ctx.Err() != nil || err == ErrThrottled) {
+			return nil, err
+		}
+
+		switch {
+		case err == nil:
+			c.endpoints.markAlive(e)
+			if index := responseHeader.Get("X-Vault-Index"); c.api.ReadAfterWrite && !read && index != "" {
+				c.endpoints.setIndex(index)
+			}
+			return response, nil
+		case isStandbyRejection(err, c.endpoints.role(e)):
+			if err.(*ResponseError).StatusCode == statusPerfStandby {
+				c.endpoints.markHealthy(e, rolePerfStandby)
+			}
+		case isEndpointFailure(err):
+			c.endpoints.markFailed(e)
+		default:
+			c.endpoints.markAlive(e)
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func validateAddress(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("invalid vault address %q: %v", address, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+		if u.Hostname() == "" {
+			return fmt.Errorf("invalid vault address %q: missing host", address)
+		}
+	case unixScheme:
+		if u.Path == "" {
+			return fmt.Errorf("invalid vault address %q: missing socket path", address)
+		}
+	case "":
+		return fmt.Errorf("invalid vault address %q: missing scheme, expected http, https or unix", address)
+	default:
+		return fmt.Errorf("invalid vault address %q: unsupported scheme %q, expected http, https or unix", address, u.Scheme)
+	}
+	return nil
+}
+
+// plainAddresses reports whether no address uses TLS, so the default CA file is not needed.
+func plainAddresses(addresses []string) bool {
+	for _, address := range addresses {
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != unixScheme) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c Client) SetAddresses(addresses []string) error {
+	if err := c.endpoints.set(addresses); err != nil {
+		return err
+	}
+	if len(addresses) > 1 {
+		c.endpoints.startProbe(c.api.ProbeInterval, c.probe)
+	}
+	return nil
+}
+
+func (c Client) Addresses() []string {
+	return c.endpoints.addresses()
+}
+
+// isStandbyRejection reports whether a node refused the request as a standby.
+// Vault answers 429 to rate limit quota violations too, so 429 means a standby
+// only for a node that sys/health reported as one; otherwise it is returned as is.
+func isStandbyRejection(err error, role nodeRole) bool {
+	responseErr, ok := err.(*ResponseError)
+	if !ok {
+		return false
+	}
+	switch responseErr.StatusCode {
+	case statusPerfStandby:
+		return true
+	case http.StatusTooManyRequests:
+		return role == roleStandby
+	}
+	return false
+}
+
+func isEndpointFailure(err error) bool {
+	responseErr, ok := err.(*ResponseError)
+	if !ok {
+		return true
+	}
+	return responseErr.StatusCode >= 500
+}
+
+// isRetryable reports whether the whole node list is worth another pass.
+func isRetryable(err error) bool {
+	if err == ErrThrottled {
+		return false
+	}
+	if _, ok := err.(*CircuitOpenError); ok {
+		return false
+	}
+	return isEndpointFailure(err) || isStandbyRejection(err, roleUnknown)
+}
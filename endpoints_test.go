@@ -0,0 +1,334 @@
+package vault
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClusterClient(t *testing.T, servers ...*httptest.Server) *Client {
+	client := newTestClient(t, servers[0], nil)
+
+	addresses := make([]string, 0, len(servers))
+	for _, server := range servers {
+		addresses = append(addresses, server.URL)
+	}
+	client.api.Addresses = addresses
+	client.endpoints, _ = newEndpointPool(addresses)
+	return client
+}
+
+func newTestNodeServer(status int, calls *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+	}))
+}
+
+func TestEndpointPoolSet(t *testing.T) {
+	pool, err := newEndpointPool([]string{"https://vault-1:8200", "https://vault-2:8200"})
+	assert.Nil(t, err)
+
+	failed := pool.candidates(false)[0]
+	pool.markFailed(failed)
+
+	assert.Nil(t, pool.set([]string{"https://vault-3:8200", "https://vault-1:8200"}))
+	assert.Equal(t, []string{"https://vault-3:8200", "https://vault-1:8200"}, pool.addresses())
+	assert.Equal(t, failed, pool.candidates(false)[1])
+
+	assert.Error(t, pool.set(nil), "")
+	assert.Error(t, pool.set([]string{"vault-1:8200"}), "")
+	assert.Error(t, pool.set([]string{"://"}), "")
+	assert.Error(t, pool.set([]string{"unix://"}), "")
+	assert.Equal(t, []string{"https://vault-3:8200", "https://vault-1:8200"}, pool.addresses())
+}
+
+func TestValidateAddress(t *testing.T) {
+	testCases := []struct {
+		name    string
+		address string
+		err     string
+	}{
+		{name: "https", address: "https://vault:8200"},
+		{name: "pathPrefix", address: "https://proxy/vault"},
+		{name: "ipv6", address: "https://[::1]:8200"},
+		{name: "unix", address: "unix:///run/vault/agent.sock"},
+		{name: "noScheme", address: "//vault:8200", err: `invalid vault address "//vault:8200": missing scheme, expected http, https or unix`},
+		{name: "scheme", address: "ftp://vault", err: `invalid vault address "ftp://vault": unsupported scheme "ftp", expected http, https or unix`},
+		{name: "noHost", address: "https:///v1", err: `invalid vault address "https:///v1": missing host`},
+		{name: "noSocket", address: "unix://", err: `invalid vault address "unix://": missing socket path`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateAddress(tc.address)
+			if tc.err == "" {
+				assert.Nil(t, err)
+				return
+			}
+			assert.Equal(t, tc.err, err.Error())
+		})
+	}
+}
+
+func TestEndpointPoolSetUnix(t *testing.T) {
+	pool, err := newEndpointPool([]string{"unix:///run/vault/agent.sock", "https://vault-1:8200"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"unix:///run/vault/agent.sock", "https://vault-1:8200"}, pool.addresses())
+}
+
+func TestEndpointPoolCandidates(t *testing.T) {
+	pool, _ := newEndpointPool([]string{"https://vault-1:8200", "https://vault-2:8200", "https://vault-3:8200"})
+	endpoints := pool.candidates(false)
+
+	pool.markFailed(endpoints[0])
+	pool.markHealthy(endpoints[2], roleActive)
+	assert.Equal(t, []*endpoint{endpoints[2], endpoints[1], endpoints[0]}, pool.candidates(false))
+
+	pool.markAlive(endpoints[0])
+	assert.Equal(t, []*endpoint{endpoints[2], endpoints[0], endpoints[1]}, pool.candidates(false))
+}
+
+func TestClientRequestFailover(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		first  int32
+		second int32
+		err    bool
+	}{
+		{name: "serverError", status: http.StatusServiceUnavailable, first: 1, second: 2},
+		{name: "clientError", status: http.StatusNotFound, first: 2, second: 0, err: true},
+		{name: "success", status: http.StatusOK, first: 2, second: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var first, second int32
+			firstServer := newTestNodeServer(tc.status, &first)
+			defer firstServer.Close()
+			secondServer := newTestNodeServer(http.StatusOK, &second)
+			defer secondServer.Close()
+
+			client := newTestClusterClient(t, firstServer, secondServer)
+			for i := 0; i < 2; i++ {
+				_, err := client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+				assert.Equal(t, tc.err, err != nil)
+			}
+
+			assert.Equal(t, tc.first, first)
+			assert.Equal(t, tc.second, second)
+		})
+	}
+}
+
+func TestClientRequestFailoverUnreachable(t *testing.T) {
+	var calls int32
+	down := newTestNodeServer(http.StatusOK, &calls)
+	down.Close()
+	up := newTestNodeServer(http.StatusOK, &calls)
+	defer up.Close()
+
+	client := newTestClusterClient(t, down, up)
+	_, err := client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), calls)
+	assert.Equal(t, up.URL, client.endpoints.candidates(false)[0].address)
+
+	client = newTestClusterClient(t, down)
+	_, err = client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+	assert.Error(t, err, "")
+}
+
+func TestClientProbe(t *testing.T) {
+	var calls int32
+	standby := newTestNodeServer(http.StatusTooManyRequests, &calls)
+	defer standby.Close()
+	sealed := newTestNodeServer(http.StatusServiceUnavailable, &calls)
+	defer sealed.Close()
+	active := newTestNodeServer(http.StatusOK, &calls)
+	defer active.Close()
+
+	client := newTestClusterClient(t, standby, sealed, active)
+	for _, e := range client.endpoints.candidates(false) {
+		client.probe(e)
+	}
+
+	var order []string
+	for _, e := range client.endpoints.candidates(false) {
+		order = append(order, e.address)
+	}
+	assert.Equal(t, []string{active.URL, standby.URL, sealed.URL}, order)
+}
+
+func TestClientSetAddresses(t *testing.T) {
+	var calls int32
+	first := newTestNodeServer(http.StatusOK, &calls)
+	defer first.Close()
+	second := newTestNodeServer(http.StatusOK, &calls)
+	defer second.Close()
+
+	client := newTestClient(t, first, nil)
+	client.api.ProbeInterval = time.Hour
+	defer client.Close()
+
+	assert.Error(t, client.SetAddresses([]string{}), "")
+	assert.Nil(t, client.SetAddresses([]string{second.URL, first.URL}))
+	assert.Equal(t, []string{second.URL, first.URL}, client.Addresses())
+
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&calls) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestEndpointPoolCandidatesStandbyReads(t *testing.T) {
+	pool, _ := newEndpointPool([]string{"https://vault-1:8200", "https://vault-2:8200", "https://vault-3:8200", "https://vault-4:8200"})
+	endpoints := pool.candidates(false)
+
+	pool.markHealthy(endpoints[0], roleStandby)
+	pool.markHealthy(endpoints[1], roleActive)
+	pool.markHealthy(endpoints[2], rolePerfStandby)
+	pool.markFailed(endpoints[3])
+
+	assert.Equal(t, []*endpoint{endpoints[1], endpoints[2], endpoints[0], endpoints[3]}, pool.candidates(false))
+	assert.Equal(t, []*endpoint{endpoints[2], endpoints[1], endpoints[0], endpoints[3]}, pool.candidates(true))
+}
+
+func TestClientRequestStandbyRejection(t *testing.T) {
+	var standbyCalls, perfStandbyCalls, activeCalls int32
+	standby := newTestNodeServer(http.StatusTooManyRequests, &standbyCalls)
+	defer standby.Close()
+	perfStandby := newTestNodeServer(statusPerfStandby, &perfStandbyCalls)
+	defer perfStandby.Close()
+	active := newTestNodeServer(http.StatusOK, &activeCalls)
+	defer active.Close()
+
+	client := newTestClusterClient(t, perfStandby, active)
+	_, err := client.request(context.Background(), "POST", "secret/path", "test_token", []byte(`{}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 1}, []int32{perfStandbyCalls, activeCalls})
+
+	// a known standby is tried before a failed node and its 429 moves on to the next one
+	client = newTestClusterClient(t, standby, active)
+	endpoints := client.endpoints.candidates(false)
+	client.endpoints.markHealthy(endpoints[0], roleStandby)
+	client.endpoints.markFailed(endpoints[1])
+	_, err = client.request(context.Background(), "POST", "secret/path", "test_token", []byte(`{}`))
+	assert.Nil(t, err)
+	assert.Equal(t, []int32{1, 2}, []int32{standbyCalls, activeCalls})
+
+	client = newTestClusterClient(t, standby, perfStandby)
+	client.endpoints.markHealthy(client.endpoints.candidates(false)[0], roleStandby)
+	_, err = client.request(context.Background(), "POST", "secret/path", "test_token", []byte(`{}`))
+	assert.Equal(t, http.StatusTooManyRequests, err.(*ResponseError).StatusCode)
+	assert.Equal(t, []int32{2, 2}, []int32{standbyCalls, perfStandbyCalls})
+}
+
+func TestClientRequestRateLimitQuota(t *testing.T) {
+	var limitedCalls, activeCalls int32
+	limited := newTestNodeServer(http.StatusTooManyRequests, &limitedCalls)
+	defer limited.Close()
+	active := newTestNodeServer(http.StatusOK, &activeCalls)
+	defer active.Close()
+
+	client := newTestClusterClient(t, limited, active)
+	client.options.MaxRetries = 2
+	_, err := client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+	assert.Equal(t, http.StatusTooManyRequests, err.(*ResponseError).StatusCode)
+	assert.Equal(t, []int32{1, 0}, []int32{limitedCalls, activeCalls})
+}
+
+func TestClientRequestStandbyReads(t *testing.T) {
+	var activeCalls, perfStandbyCalls int32
+	active := newTestNodeServer(http.StatusOK, &activeCalls)
+	defer active.Close()
+	perfStandby := newTestNodeServer(http.StatusOK, &perfStandbyCalls)
+	defer perfStandby.Close()
+
+	client := newTestClusterClient(t, active, perfStandby)
+	client.api.StandbyReads = true
+	endpoints := client.endpoints.candidates(false)
+	client.endpoints.markHealthy(endpoints[0], roleActive)
+	client.endpoints.markHealthy(endpoints[1], rolePerfStandby)
+
+	_, _ = client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+	_, _ = client.request(context.Background(), "POST", "secret/path", "test_token", []byte(`{}`))
+	assert.Equal(t, int32(1), activeCalls)
+	assert.Equal(t, int32(1), perfStandbyCalls)
+}
+
+func TestClientRequestRedirect(t *testing.T) {
+	active := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		assert.Equal(t, "POST", req.Method)
+		assert.Equal(t, `{"key":"value"}`, string(body))
+		assert.Equal(t, "test_token", req.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer active.Close()
+	standby := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Redirect(w, req, active.URL+req.URL.Path, http.StatusTemporaryRedirect)
+	}))
+	defer standby.Close()
+
+	client := newTestClusterClient(t, standby)
+	response, err := client.request(context.Background(), "POST", "secret/path", "test_token", []byte(`{"key":"value"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, `{"data":{}}`, string(response))
+}
+
+func TestClientRequestReadAfterWrite(t *testing.T) {
+	var headers []http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		headers = append(headers, req.Header.Clone())
+		if req.Method == "POST" {
+			w.Header().Set("X-Vault-Index", "index-1")
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClusterClient(t, server)
+	client.api.ReadAfterWrite = true
+
+	_, _ = client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+	_, _ = client.request(context.Background(), "POST", "secret/path", "test_token", []byte(`{}`))
+	_, _ = client.request(context.Background(), "GET", "secret/path", "test_token", nil)
+
+	assert.Equal(t, "", headers[0].Get("X-Vault-Index"))
+	assert.Equal(t, "", headers[1].Get("X-Vault-Index"))
+	assert.Equal(t, "index-1", headers[2].Get("X-Vault-Index"))
+	assert.Equal(t, "forward-active-node", headers[2].Get("X-Vault-Inconsistent"))
+}
+
+func TestClientRequestRetry(t *testing.T) {
+	testCases := []struct {
+		name       string
+		maxRetries int
+		err        bool
+		calls      int32
+	}{
+		{name: "noRetries", maxRetries: 0, err: true, calls: 1},
+		{name: "notEnoughRetries", maxRetries: 1, err: true, calls: 2},
+		{name: "recovered", maxRetries: 3, calls: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				if atomic.AddInt32(&calls, 1) < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server, &ClientOptions{MaxRetries: tc.maxRetries, RetryWait: time.Millisecond})
+			_, err := client.request(context.Background(), "GET", "secret/path", "", nil)
+			assert.Equal(t, tc.err, err != nil)
+			assert.Equal(t, tc.calls, atomic.LoadInt32(&calls))
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+const (
+	envAddress    = "VAULT_ADDR"
+	envCACert     = "VAULT_CACERT"
+	envCAPath     = "VAULT_CAPATH"
+	envClientCert = "VAULT_CLIENT_CERT"
+	envClientKey  = "VAULT_CLIENT_KEY"
+	envToken      = "VAULT_TOKEN"
+	envNamespace  = "VAULT_NAMESPACE"
+	envRoleId     = "VAULT_ROLE_ID"
+	envSecretId   = "VAULT_SECRET_ID"
+	envSkipVerify = "VAULT_SKIP_VERIFY"
+	envMaxRetries = "VAULT_MAX_RETRIES"
+)
+
+// NewClientFromEnv creates a client configured by the Vault CLI environment
+// variables. Fields set in options and api take precedence over the environment.
+// Without VAULT_CACERT and VAULT_CAPATH the system CA pool is used.
+func NewClientFromEnv(options *ClientOptions, api *ClientApi) (*Client, error) {
+	cliOpt, err := envClientOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	return NewCustomClient(os.Getenv(envRoleId), os.Getenv(envSecretId), cliOpt, envClientApi(api))
+}
+
+func envClientOptions(options *ClientOptions) (*ClientOptions, error) {
+	cliOpt := &ClientOptions{}
+	if options != nil {
+		*cliOpt = *options
+	}
+
+	setFromEnv(&cliOpt.CertFilePath, envCACert)
+	setFromEnv(&cliOpt.TLS.CAPath, envCAPath)
+	setFromEnv(&cliOpt.TLS.ClientCertFile, envClientCert)
+	setFromEnv(&cliOpt.TLS.ClientKeyFile, envClientKey)
+	setFromEnv(&cliOpt.Token, envToken)
+
+	if value := os.Getenv(envSkipVerify); value != "" && !cliOpt.TLS.InsecureSkipVerify {
+		skipVerify, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", envSkipVerify, value)
+		}
+		cliOpt.TLS.InsecureSkipVerify = skipVerify
+	}
+
+	if value := os.Getenv(envMaxRetries); value != "" && cliOpt.MaxRetries == 0 {
+		maxRetries, err := strconv.Atoi(value)
+		if err != nil || maxRetries < 0 {
+			return nil, fmt.Errorf("invalid %s: %s", envMaxRetries, value)
+		}
+		cliOpt.MaxRetries = maxRetries
+	}
+
+	if cliOpt.CertFilePath == "" && cliOpt.TLS.CAPath == "" {
+		cliOpt.TLS.UseSystemRoots = true
+	}
+	return cliOpt, nil
+}
+
+func envClientApi(api *ClientApi) *ClientApi {
+	cliApi := &ClientApi{}
+	if api != nil {
+		*cliApi = *api
+	}
+
+	if address := os.Getenv(envAddress); address != "" && cliApi.Host == "" && cliApi.Address == "" && len(cliApi.Addresses) == 0 {
+		cliApi.Addresses = []string{address}
+	}
+	setFromEnv(&cliApi.Namespace, envNamespace)
+	return cliApi
+}
+
+func setFromEnv(field *string, name string) {
+	if *field == "" {
+		*field = os.Getenv(name)
+	}
+}
@@ -0,0 +1,131 @@
+package vault
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func setTestEnv(t *testing.T, env map[string]string) {
+	for _, name := range []string{
+		envAddress, envCACert, envCAPath, envClientCert, envClientKey, envToken,
+		envNamespace, envRoleId, envSecretId, envSkipVerify, envMaxRetries,
+	} {
+		value, ok := os.LookupEnv(name)
+		_ = os.Unsetenv(name)
+		t.Cleanup(func() {
+			if ok {
+				_ = os.Setenv(name, value)
+			} else {
+				_ = os.Unsetenv(name)
+			}
+		})
+	}
+	for name, value := range env {
+		_ = os.Setenv(name, value)
+	}
+}
+
+func TestEnvClientOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		env     map[string]string
+		options *ClientOptions
+		expect  *ClientOptions
+	}{
+		{
+			name:   "empty",
+			expect: &ClientOptions{TLS: TLSOptions{UseSystemRoots: true}},
+		},
+		{
+			name: "environment",
+			env: map[string]string{
+				envCACert: "/env/ca.pem", envCAPath: "/env/ca", envClientCert: "/env/client.pem", envClientKey: "/env/client.key",
+				envToken: "env_token", envSkipVerify: "true", envMaxRetries: "3",
+			},
+			expect: &ClientOptions{
+				CertFilePath: "/env/ca.pem",
+				Token:        "env_token",
+				MaxRetries:   3,
+				TLS: TLSOptions{
+					CAPath: "/env/ca", ClientCertFile: "/env/client.pem", ClientKeyFile: "/env/client.key", InsecureSkipVerify: true,
+				},
+			},
+		},
+		{
+			name:    "explicitOverrides",
+			env:     map[string]string{envCACert: "/env/ca.pem", envToken: "env_token", envMaxRetries: "3"},
+			options: &ClientOptions{CertFilePath: "/etc/ca.pem", Token: "token", MaxRetries: 1},
+			expect:  &ClientOptions{CertFilePath: "/etc/ca.pem", Token: "token", MaxRetries: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestEnv(t, tc.env)
+
+			actual, err := envClientOptions(tc.options)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, actual)
+		})
+	}
+}
+
+func TestEnvClientOptionsNegative(t *testing.T) {
+	testCases := []struct {
+		name string
+		env  map[string]string
+	}{
+		{name: "skipVerify", env: map[string]string{envSkipVerify: "maybe"}},
+		{name: "maxRetries", env: map[string]string{envMaxRetries: "many"}},
+		{name: "negativeMaxRetries", env: map[string]string{envMaxRetries: "-1"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setTestEnv(t, tc.env)
+
+			_, err := envClientOptions(nil)
+			assert.Error(t, err, "")
+		})
+	}
+}
+
+func TestEnvClientApi(t *testing.T) {
+	setTestEnv(t, map[string]string{envAddress: "https://env-vault:8200", envNamespace: "env"})
+
+	assert.Equal(t, &ClientApi{Addresses: []string{"https://env-vault:8200"}, Namespace: "env"}, envClientApi(nil))
+	assert.Equal(t, &ClientApi{Host: "https://vault", Namespace: "team"}, envClientApi(&ClientApi{Host: "https://vault", Namespace: "team"}))
+	assert.Equal(t, &ClientApi{Address: "https://vault:8200", Namespace: "env"}, envClientApi(&ClientApi{Address: "https://vault:8200"}))
+}
+
+func TestNewClientFromEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/secret/path", req.URL.Path)
+		assert.Equal(t, "env_token", req.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "team", req.Header.Get("X-Vault-Namespace"))
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	setTestEnv(t, map[string]string{
+		envAddress: server.URL, envToken: "env_token", envNamespace: "team", envRoleId: "env_role", envSecretId: "env_secret",
+	})
+	client, err := NewClientFromEnv(&ClientOptions{TokenFilePath: filepath.Join(dir, ".token")}, nil)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	assert.Equal(t, "env_role", client.RoleId)
+	assert.Equal(t, "env_secret", client.SecretId)
+	assert.Equal(t, []string{server.URL}, client.Addresses())
+
+	_, err = client.Get("secret/path")
+	assert.Nil(t, err)
+}
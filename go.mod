@@ -2,4 +2,8 @@ module github.com/mr-chelyshkin/go-vault
 
 go 1.14
 
-require github.com/stretchr/testify v1.5.1
+require (
+	github.com/BurntSushi/toml v0.3.0
+	github.com/stretchr/testify v1.5.1
+	gopkg.in/yaml.v2 v2.4.0
+)
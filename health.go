@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+const sealStatusLink = "sys/seal-status"
+
+type HealthState string
+
+const (
+	HealthActive         HealthState = "active"
+	HealthStandby        HealthState = "standby"
+	HealthPerfStandby    HealthState = "performance_standby"
+	HealthDrSecondary    HealthState = "dr_secondary"
+	HealthNotInitialized HealthState = "not_initialized"
+	HealthSealed         HealthState = "sealed"
+)
+
+var healthStates = map[int]HealthState{
+	http.StatusOK:                 HealthActive,
+	http.StatusTooManyRequests:    HealthStandby,
+	472:                           HealthDrSecondary,
+	statusPerfStandby:             HealthPerfStandby,
+	http.StatusNotImplemented:     HealthNotInitialized,
+	http.StatusServiceUnavailable: HealthSealed,
+}
+
+type HealthOptions struct {
+	StandbyOk     bool // standby узел отвечает 200
+	PerfStandbyOk bool // performance standby узел отвечает 200
+}
+
+type Health struct {
+	State      HealthState `json:"-"`
+	StatusCode int         `json:"-"`
+	Address    string      `json:"-"`
+
+	Initialized                bool   `json:"initialized"`
+	Sealed                     bool   `json:"sealed"`
+	Standby                    bool   `json:"standby"`
+	PerformanceStandby         bool   `json:"performance_standby"`
+	ReplicationPerformanceMode string `json:"replication_performance_mode"`
+	ReplicationDrMode          string `json:"replication_dr_mode"`
+	ServerTimeUtc              int64  `json:"server_time_utc"`
+	Version                    string `json:"version"`
+	ClusterName                string `json:"cluster_name"`
+	ClusterId                  string `json:"cluster_id"`
+}
+
+type SealStatus struct {
+	Type         string `json:"type"`
+	Initialized  bool   `json:"initialized"`
+	Sealed       bool   `json:"sealed"`
+	Threshold    int    `json:"t"`
+	Shares       int    `json:"n"`
+	Progress     int    `json:"progress"`
+	Nonce        string `json:"nonce"`
+	Version      string `json:"version"`
+	BuildDate    string `json:"build_date"`
+	Migration    bool   `json:"migration"`
+	ClusterName  string `json:"cluster_name"`
+	ClusterId    string `json:"cluster_id"`
+	RecoverySeal bool   `json:"recovery_seal"`
+	StorageType  string `json:"storage_type"`
+}
+
+// Health queries sys/health on the preferred node. Status codes that describe
+// the node state (standby, sealed, not initialized) are returned as Health.State,
+// only unreachable nodes and unexpected responses produce an error.
+func (c Client) Health(ctx context.Context, options *HealthOptions) (*Health, error) {
+	var query string
+	if options != nil {
+		values := url.Values{}
+		if options.StandbyOk {
+			values.Set("standbyok", "true")
+		}
+		if options.PerfStandbyOk {
+			values.Set("perfstandbyok", "true")
+		}
+		query = values.Encode()
+	}
+
+	var lastErr error
+	for _, e := range c.endpoints.candidates(false) {
+		healthUrl := c.api.endpointUrl(e.address, healthLink)
+		if query != "" {
+			healthUrl += "?" + query
+		}
+
+		response, _, err := c.actions.send(ctx, "GET", healthUrl, "", nil, nil)
+		if err == nil || isResponseError(err) {
+			return decodeHealth(e.address, response, err)
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+func (c Client) SealStatus(ctx context.Context) (*SealStatus, error) {
+	response, err := c.request(ctx, "GET", sealStatusLink, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var status SealStatus
+	err = json.Unmarshal(response, &status)
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func decodeHealth(address string, response []byte, err error) (*Health, error) {
+	statusCode := http.StatusOK
+	if err != nil {
+		statusCode = err.(*ResponseError).StatusCode
+	}
+
+	state, ok := healthStates[statusCode]
+	if !ok {
+		return nil, err
+	}
+
+	health := &Health{State: state, StatusCode: statusCode, Address: address}
+	if len(response) > 0 {
+		if err := json.Unmarshal(response, health); err != nil {
+			return nil, err
+		}
+	}
+
+	if statusCode == http.StatusOK {
+		switch {
+		case health.PerformanceStandby:
+			health.State = HealthPerfStandby
+		case health.Standby:
+			health.State = HealthStandby
+		}
+	}
+	return health, nil
+}
@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientHealth(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		body   string
+		expect HealthState
+		err    bool
+	}{
+		{name: "active", status: http.StatusOK, body: `{"initialized":true,"version":"1.5.0"}`, expect: HealthActive},
+		{name: "standbyOk", status: http.StatusOK, body: `{"initialized":true,"standby":true}`, expect: HealthStandby},
+		{name: "perfStandbyOk", status: http.StatusOK, body: `{"initialized":true,"standby":true,"performance_standby":true}`, expect: HealthPerfStandby},
+		{name: "standby", status: http.StatusTooManyRequests, body: `{"initialized":true,"standby":true}`, expect: HealthStandby},
+		{name: "drSecondary", status: 472, body: `{"initialized":true}`, expect: HealthDrSecondary},
+		{name: "perfStandby", status: 473, body: `{"initialized":true}`, expect: HealthPerfStandby},
+		{name: "notInitialized", status: http.StatusNotImplemented, body: `{"initialized":false,"sealed":true}`, expect: HealthNotInitialized},
+		{name: "sealed", status: http.StatusServiceUnavailable, body: `{"initialized":true,"sealed":true}`, expect: HealthSealed},
+		{name: "emptyBody", status: http.StatusServiceUnavailable, expect: HealthSealed},
+		{name: "unexpected", status: http.StatusInternalServerError, err: true},
+		{name: "brokenBody", status: http.StatusOK, body: `{`, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/v1/sys/health", req.URL.Path)
+				assert.Equal(t, "", req.Header.Get("X-Vault-Token"))
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server, nil)
+			health, err := client.Health(context.Background(), nil)
+			if tc.err {
+				assert.Nil(t, health)
+				assert.Error(t, err, "")
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, health.State)
+			assert.Equal(t, tc.status, health.StatusCode)
+			assert.Equal(t, server.URL, health.Address)
+		})
+	}
+}
+
+func TestClientHealthOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options *HealthOptions
+		query   string
+	}{
+		{name: "nil", options: nil, query: ""},
+		{name: "empty", options: &HealthOptions{}, query: ""},
+		{name: "standbyOk", options: &HealthOptions{StandbyOk: true}, query: "standbyok=true"},
+		{name: "both", options: &HealthOptions{StandbyOk: true, PerfStandbyOk: true}, query: "perfstandbyok=true&standbyok=true"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/v1/sys/health", req.URL.Path)
+				assert.Equal(t, tc.query, req.URL.RawQuery)
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server, nil)
+			_, err := client.Health(context.Background(), tc.options)
+			assert.Nil(t, err)
+		})
+	}
+}
+
+func TestClientHealthFailover(t *testing.T) {
+	var calls int32
+	down := newTestNodeServer(http.StatusOK, &calls)
+	down.Close()
+	sealed := newTestNodeServer(http.StatusServiceUnavailable, &calls)
+	defer sealed.Close()
+
+	client := newTestClusterClient(t, down, sealed)
+	health, err := client.Health(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, HealthSealed, health.State)
+	assert.Equal(t, sealed.URL, health.Address)
+
+	client = newTestClusterClient(t, down)
+	_, err = client.Health(context.Background(), nil)
+	assert.Error(t, err, "")
+}
+
+func TestClientSealStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/sys/seal-status", req.URL.Path)
+		_, _ = w.Write([]byte(`{"type":"shamir","initialized":true,"sealed":true,"t":3,"n":5,"progress":1,"version":"1.5.0"}`))
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	status, err := client.SealStatus(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, &SealStatus{Type: "shamir", Initialized: true, Sealed: true, Threshold: 3, Shares: 5, Progress: 1, Version: "1.5.0"}, status)
+}
@@ -0,0 +1,170 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrThrottled = errors.New("vault request throttled by client limits")
+
+type LimitOptions struct {
+	Rate        float64 // запросов в секунду, 0 - без ограничения
+	Burst       int     // запросов подряд без ожидания, по умолчанию max(1, Rate)
+	MaxInFlight int     // одновременных запросов, 0 - без ограничения
+	FailFast    bool    // возвращать ErrThrottled вместо ожидания
+}
+
+type LimitStats struct {
+	Allowed   uint64 // запросы, прошедшие ограничения
+	Throttled uint64 // запросы, которые ждали rate limit или свободный слот
+	Rejected  uint64 // запросы, отклоненные с ErrThrottled
+	InFlight  int64  // запросы, выполняемые сейчас
+}
+
+type requestLimiter struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	slots    chan struct{}
+	failFast bool
+
+	allowed   uint64
+	throttled uint64
+	rejected  uint64
+	inFlight  int64
+
+	now func() time.Time
+}
+
+func newRequestLimiter(options *LimitOptions) *requestLimiter {
+	if options == nil {
+		return nil
+	}
+
+	l := &requestLimiter{
+		rate:     options.Rate,
+		burst:    float64(options.Burst),
+		failFast: options.FailFast,
+		now:      time.Now,
+	}
+	if l.burst < 1 {
+		l.burst = l.rate
+		if l.burst < 1 {
+			l.burst = 1
+		}
+	}
+	l.tokens = l.burst
+	l.last = l.now()
+
+	if options.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, options.MaxInFlight)
+	}
+	return l
+}
+
+// acquire waits for the rate limit and a free slot; release must be called
+// when the request is done.
+func (l *requestLimiter) acquire(ctx context.Context) (func(), error) {
+	throttled := false
+
+	if wait, err := l.reserve(); err != nil {
+		atomic.AddUint64(&l.rejected, 1)
+		return nil, err
+	} else if wait > 0 {
+		throttled = true
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			l.cancel()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			if l.failFast {
+				l.cancel()
+				atomic.AddUint64(&l.rejected, 1)
+				return nil, ErrThrottled
+			}
+			throttled = true
+			select {
+			case <-ctx.Done():
+				l.cancel()
+				return nil, ctx.Err()
+			case l.slots <- struct{}{}:
+			}
+		}
+	}
+
+	if throttled {
+		atomic.AddUint64(&l.throttled, 1)
+	}
+	atomic.AddUint64(&l.allowed, 1)
+	atomic.AddInt64(&l.inFlight, 1)
+
+	return func() {
+		atomic.AddInt64(&l.inFlight, -1)
+		if l.slots != nil {
+			<-l.slots
+		}
+	}, nil
+}
+
+// reserve takes a token from the bucket and returns how long to wait for it.
+func (l *requestLimiter) reserve() (time.Duration, error) {
+	if l.rate <= 0 {
+		return 0, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0, nil
+	}
+	if l.failFast {
+		return 0, ErrThrottled
+	}
+
+	l.tokens--
+	return time.Duration(-l.tokens / l.rate * float64(time.Second)), nil
+}
+
+// cancel returns the token taken by reserve for a request that was not sent.
+func (l *requestLimiter) cancel() {
+	if l.rate <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens++
+}
+
+func (l *requestLimiter) stats() LimitStats {
+	return LimitStats{
+		Allowed:   atomic.LoadUint64(&l.allowed),
+		Throttled: atomic.LoadUint64(&l.throttled),
+		Rejected:  atomic.LoadUint64(&l.rejected),
+		InFlight:  atomic.LoadInt64(&l.inFlight),
+	}
+}
@@ -0,0 +1,137 @@
+package vault
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestRequestLimiter(options *LimitOptions, now *time.Time) *requestLimiter {
+	l := newRequestLimiter(options)
+	l.now = func() time.Time { return *now }
+	l.last = *now
+	return l
+}
+
+func TestRequestLimiterRate(t *testing.T) {
+	now := time.Now()
+	l := newTestRequestLimiter(&LimitOptions{Rate: 10, Burst: 2, FailFast: true}, &now)
+
+	for i := 0; i < 2; i++ {
+		release, err := l.acquire(context.Background())
+		assert.Nil(t, err)
+		release()
+	}
+	_, err := l.acquire(context.Background())
+	assert.Equal(t, ErrThrottled, err)
+
+	now = now.Add(100 * time.Millisecond)
+	_, err = l.acquire(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, LimitStats{Allowed: 3, Rejected: 1, InFlight: 1}, l.stats())
+}
+
+func TestRequestLimiterReserve(t *testing.T) {
+	now := time.Now()
+	l := newTestRequestLimiter(&LimitOptions{Rate: 10}, &now)
+	assert.Equal(t, float64(10), l.burst)
+
+	for i := 0; i < 10; i++ {
+		wait, _ := l.reserve()
+		assert.Equal(t, time.Duration(0), wait)
+	}
+	wait, err := l.reserve()
+	assert.Nil(t, err)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	wait, _ = l.reserve()
+	assert.Equal(t, 200*time.Millisecond, wait)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = l.acquire(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	wait, _ = l.reserve()
+	assert.Equal(t, 300*time.Millisecond, wait)
+}
+
+func TestRequestLimiterSlotReturnsToken(t *testing.T) {
+	now := time.Now()
+
+	l := newTestRequestLimiter(&LimitOptions{Rate: 10, Burst: 2, MaxInFlight: 1, FailFast: true}, &now)
+	release, err := l.acquire(context.Background())
+	assert.Nil(t, err)
+	_, err = l.acquire(context.Background())
+	assert.Equal(t, ErrThrottled, err)
+	release()
+
+	_, err = l.acquire(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, LimitStats{Allowed: 2, Rejected: 1, InFlight: 1}, l.stats())
+
+	l = newTestRequestLimiter(&LimitOptions{Rate: 10, Burst: 2, MaxInFlight: 1}, &now)
+	release, err = l.acquire(context.Background())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	release()
+
+	wait, err := l.reserve()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), wait)
+}
+
+func TestRequestLimiterInFlight(t *testing.T) {
+	l := newRequestLimiter(&LimitOptions{MaxInFlight: 1, FailFast: true})
+
+	release, err := l.acquire(context.Background())
+	assert.Nil(t, err)
+	_, err = l.acquire(context.Background())
+	assert.Equal(t, ErrThrottled, err)
+	release()
+
+	l = newRequestLimiter(&LimitOptions{MaxInFlight: 1})
+	release, _ = l.acquire(context.Background())
+
+	acquired := make(chan struct{})
+	go func() {
+		next, err := l.acquire(context.Background())
+		assert.Nil(t, err)
+		next()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired over MaxInFlight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.Equal(t, int64(1), l.stats().InFlight)
+
+	release()
+	<-acquired
+	assert.Equal(t, LimitStats{Allowed: 2, Throttled: 1}, l.stats())
+}
+
+func TestClientLimitFailFast(t *testing.T) {
+	server := newTestVaultServer(t, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	client.actions.limiter = newRequestLimiter(&LimitOptions{Rate: 0.001, Burst: 3, FailFast: true})
+
+	_, err := client.Get("secret/path")
+	assert.Nil(t, err)
+	_, err = client.Get("secret/path")
+	assert.Equal(t, ErrThrottled, err)
+	assert.Equal(t, uint64(1), client.LimitStats().Rejected)
+	assert.Equal(t, 1, len(client.endpoints.candidates(false)))
+	assert.True(t, client.endpoints.candidates(false)[0].failedAt.IsZero())
+}
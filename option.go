@@ -0,0 +1,263 @@
+package vault
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Option configures a client created by New.
+type Option func(c *clientConfig) error
+
+type clientConfig struct {
+	credentials
+	options ClientOptions
+	api     *ClientApi
+
+	// legacy is set by NewBasicClient and NewCustomClient: they never checked auth
+	// and may rely on an existing token file or a custom TokenStore.
+	legacy bool
+}
+
+// AuthMethod is passed to WithAuth: AppRole, StaticToken or Agent.
+type AuthMethod interface {
+	apply(c *clientConfig) error
+}
+
+type appRoleAuth credentials
+
+type tokenAuth string
+
+type agentAuth struct{}
+
+func AppRole(roleId, secretId string) AuthMethod {
+	return appRoleAuth{RoleId: roleId, SecretId: secretId}
+}
+
+func StaticToken(token string) AuthMethod {
+	return tokenAuth(token)
+}
+
+func Agent() AuthMethod {
+	return agentAuth{}
+}
+
+func (a appRoleAuth) apply(c *clientConfig) error {
+	if a.RoleId == "" {
+		return errors.New("approle role id is empty")
+	}
+	c.credentials = credentials(a)
+	c.options.Token = ""
+	c.clientApi().Agent = false
+	return nil
+}
+
+func (a tokenAuth) apply(c *clientConfig) error {
+	if a == "" {
+		return errors.New("vault token is empty")
+	}
+	c.credentials = credentials{}
+	c.options.Token = string(a)
+	c.clientApi().Agent = false
+	return nil
+}
+
+func (a agentAuth) apply(c *clientConfig) error {
+	c.credentials = credentials{}
+	c.options.Token = ""
+	c.clientApi().Agent = true
+	return nil
+}
+
+// New creates a client. Addresses, CA files and auth are checked here,
+// so a misconfigured client fails at start rather than on the first request.
+func New(opts ...Option) (*Client, error) {
+	config := &clientConfig{}
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return nil, err
+		}
+	}
+
+	cliOpt := getClientOptions(&config.options)
+	cliApi := getClientApi(config.api)
+	if config.options.CertFilePath == "" && plainAddresses(cliApi.addresses()) {
+		cliOpt.CertFilePath = ""
+	}
+
+	if !config.legacy && config.RoleId == "" && cliOpt.Token == "" && !cliApi.Agent {
+		return nil, errors.New("vault auth is not configured")
+	}
+
+	if err := cliApi.validateHostPort(); err != nil {
+		return nil, err
+	}
+
+	endpoints, err := newEndpointPool(cliApi.addresses())
+	if err != nil {
+		return nil, err
+	}
+
+	actions, err := newActions(cliOpt)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{
+		credentials: config.credentials,
+		options:     cliOpt,
+		actions:     actions,
+		api:         cliApi,
+		tokens:      getTokenStore(cliOpt),
+		cache:       newSecretCache(cliOpt.Cache),
+		endpoints:   endpoints,
+	}
+	if len(cliApi.Addresses) > 1 {
+		endpoints.startProbe(cliApi.ProbeInterval, client.probe)
+	}
+	return client, nil
+}
+
+func (c *clientConfig) clientApi() *ClientApi {
+	if c.api == nil {
+		c.api = &ClientApi{}
+	}
+	return c.api
+}
+
+func WithAddress(addresses ...string) Option {
+	return func(c *clientConfig) error {
+		if len(addresses) == 0 {
+			return errors.New("vault addresses list is empty")
+		}
+		for _, address := range addresses {
+			if err := validateAddress(address); err != nil {
+				return err
+			}
+		}
+		c.clientApi().Addresses = addresses
+		return nil
+	}
+}
+
+func WithNamespace(namespace string) Option {
+	return func(c *clientConfig) error {
+		c.clientApi().Namespace = namespace
+		return nil
+	}
+}
+
+func WithAuth(method AuthMethod) Option {
+	return func(c *clientConfig) error {
+		if method == nil {
+			return errors.New("vault auth method is nil")
+		}
+		return method.apply(c)
+	}
+}
+
+func WithCACert(path string) Option {
+	return func(c *clientConfig) error {
+		if _, err := loadCertificates(path); err != nil {
+			return err
+		}
+		c.options.CertFilePath = path
+		return nil
+	}
+}
+
+func WithCAPath(path string) Option {
+	return func(c *clientConfig) error {
+		c.options.TLS.CAPath = path
+		return nil
+	}
+}
+
+func WithClientCert(certFile, keyFile string) Option {
+	return func(c *clientConfig) error {
+		c.options.TLS.ClientCertFile = certFile
+		c.options.TLS.ClientKeyFile = keyFile
+		return nil
+	}
+}
+
+// WithTLS replaces all TLS options, including CA path and client certificate.
+func WithTLS(options TLSOptions) Option {
+	return func(c *clientConfig) error {
+		c.options.TLS = options
+		return nil
+	}
+}
+
+func WithTokenStore(store TokenStore) Option {
+	return func(c *clientConfig) error {
+		c.options.TokenStore = store
+		return nil
+	}
+}
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *clientConfig) error {
+		c.options.HTTPClient = httpClient
+		return nil
+	}
+}
+
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *clientConfig) error {
+		c.options.Middleware = append(c.options.Middleware, middleware...)
+		return nil
+	}
+}
+
+func WithRetry(maxRetries int, wait time.Duration) Option {
+	return func(c *clientConfig) error {
+		if maxRetries < 0 {
+			return errors.New("max retries must not be negative")
+		}
+		c.options.MaxRetries = maxRetries
+		c.options.RetryWait = wait
+		return nil
+	}
+}
+
+func WithLogger(logger Logger) Option {
+	return func(c *clientConfig) error {
+		c.options.Logger = logger
+		return nil
+	}
+}
+
+func WithCache(options *CacheOptions) Option {
+	return func(c *clientConfig) error {
+		c.options.Cache = options
+		return nil
+	}
+}
+
+func withClientOptions(options *ClientOptions) Option {
+	return func(c *clientConfig) error {
+		if options != nil {
+			c.options = *options
+		}
+		return nil
+	}
+}
+
+func withClientApi(api *ClientApi) Option {
+	return func(c *clientConfig) error {
+		if api != nil {
+			copied := *api
+			c.api = &copied
+		}
+		return nil
+	}
+}
+
+func withCredentials(roleId, secretId string) Option {
+	return func(c *clientConfig) error {
+		c.credentials = credentials{RoleId: roleId, SecretId: secretId}
+		c.legacy = true
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNew(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "static_token", req.Header.Get("X-Vault-Token"))
+		assert.Equal(t, "team", req.Header.Get("X-Vault-Namespace"))
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	logger := &testLogger{}
+	client, err := New(
+		WithAddress(server.URL),
+		WithNamespace("team"),
+		WithAuth(StaticToken("static_token")),
+		WithTLS(TLSOptions{UseSystemRoots: true}),
+		WithTokenStore(NoopTokenStore{}),
+		WithRetry(2, time.Millisecond),
+		WithLogger(logger),
+	)
+	assert.Nil(t, err)
+	defer client.Close()
+
+	assert.Equal(t, 2, client.options.MaxRetries)
+	assert.Equal(t, Logger(logger), client.options.Logger)
+	assert.Equal(t, NoopTokenStore{}, client.tokens)
+
+	_, err = client.Get("secret/path")
+	assert.Nil(t, err)
+}
+
+func TestNewInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	}))
+	defer server.Close()
+
+	tlsOptions := TLSOptions{InsecureSkipVerify: true}
+	client, err := New(WithAddress(server.URL), WithAuth(StaticToken("static_token")), WithTLS(tlsOptions), WithTokenStore(NoopTokenStore{}))
+	assert.Nil(t, err)
+	defer client.Close()
+	assert.Equal(t, "", client.options.CertFilePath)
+
+	_, err = client.Get("secret/path")
+	assert.Nil(t, err)
+
+	legacy, err := NewCustomClient("roleId", "secretId", &ClientOptions{TLS: tlsOptions}, &ClientApi{Address: server.URL})
+	assert.Nil(t, err)
+	assert.Equal(t, "", legacy.options.CertFilePath)
+	assert.Nil(t, legacy.Close())
+}
+
+func TestNewNegative(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	caFile := writeTestFile(t, dir, "ca.pem", newTestCA(t, "ca").certPEM)
+	emptyFile := writeTestFile(t, dir, "empty.pem", nil)
+
+	testCases := []struct {
+		name string
+		opts []Option
+		err  string
+	}{
+		{name: "noAuth", opts: []Option{WithCACert(caFile)}, err: "vault auth is not configured"},
+		{name: "emptyRoleId", opts: []Option{WithAuth(AppRole("", "secret"))}, err: "approle role id is empty"},
+		{name: "emptyToken", opts: []Option{WithAuth(StaticToken(""))}, err: "vault token is empty"},
+		{name: "nilAuth", opts: []Option{WithAuth(nil)}, err: "vault auth method is nil"},
+		{name: "invalidAddress", opts: []Option{WithAddress("vault:8200")}, err: `invalid vault address "vault:8200": unsupported scheme "vault", expected http, https or unix`},
+		{name: "noAddresses", opts: []Option{WithAddress()}, err: "vault addresses list is empty"},
+		{
+			name: "hostTwoPorts", opts: []Option{withClientApi(&ClientApi{Host: "https://vault:8200", Port: "8300"}), WithAuth(Agent())},
+			err: `invalid vault address "https://vault:8200": host already has port 8200, port 8300 is not applied`,
+		},
+		{name: "emptyCACert", opts: []Option{WithCACert(emptyFile)}, err: "no certificates found in CA file " + emptyFile},
+		{name: "missingCAPath", opts: []Option{WithAuth(Agent()), WithCAPath(filepath.Join(dir, "not_exist"))}},
+		{name: "negativeRetries", opts: []Option{WithRetry(-1, 0)}, err: "max retries must not be negative"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := New(tc.opts...)
+			assert.Nil(t, client)
+			assert.Error(t, err, "")
+			if tc.err != "" {
+				assert.Equal(t, tc.err, err.Error())
+			}
+		})
+	}
+}
+
+func TestNewPlainAddressesWithoutCA(t *testing.T) {
+	testCases := []struct {
+		name      string
+		addresses []string
+		certFile  string
+	}{
+		{name: "unix", addresses: []string{"unix:///run/vault/agent.sock"}},
+		{name: "http", addresses: []string{"http://127.0.0.1:8200", "unix:///run/vault/agent.sock"}},
+		{name: "https", addresses: []string{"https://vault:8200", "unix:///run/vault/agent.sock"}, certFile: baseCertFile},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := New(WithAddress(tc.addresses...), WithAuth(Agent()))
+			if tc.certFile != "" {
+				if _, statErr := os.Stat(tc.certFile); statErr != nil {
+					assert.Error(t, err, "")
+					return
+				}
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.certFile, client.options.CertFilePath)
+			assert.Nil(t, client.Close())
+		})
+	}
+}
+
+func TestNewAuthMethods(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+	caFile := writeTestFile(t, dir, "ca.pem", newTestCA(t, "ca").certPEM)
+
+	api := &ClientApi{Addresses: []string{"https://vault-1:8200"}}
+	client, err := New(withClientApi(api), WithCACert(caFile), WithAuth(StaticToken("token")), WithAuth(Agent()))
+	assert.Nil(t, err)
+	assert.True(t, client.api.Agent)
+	assert.Equal(t, "", client.options.Token)
+	assert.False(t, api.Agent)
+
+	client, err = New(WithCACert(caFile), WithAuth(Agent()), WithAuth(AppRole("role", "secret")))
+	assert.Nil(t, err)
+	assert.False(t, client.api.Agent)
+	assert.Equal(t, credentials{RoleId: "role", SecretId: "secret"}, client.credentials)
+	assert.Equal(t, getBaseClientApi().Host, client.api.Host)
+}
@@ -1,9 +1,13 @@
 package vault
 
 import (
+	"context"
+	"net"
+	"net/http"
 	"os/user"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 const (
@@ -11,9 +15,44 @@ const (
 	baseCertFile     = "/etc/ssl/search/ca.pem"
 )
 
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
 type ClientOptions struct {
 	TokenFilePath string
 	CertFilePath  string
+
+	TLS TLSOptions
+
+	Token         string
+	TokenStore    TokenStore
+	RevokeOnClose bool
+	Renewal       RenewalPolicy
+	Cache         *CacheOptions
+	Limit         *LimitOptions
+	Breaker       *BreakerOptions
+	MaxRetries    int
+	RetryWait     time.Duration
+
+	Logger Logger // предупреждения клиента, nil - не писать
+
+	HTTPClient *http.Client // собственный http клиент, CertFilePath и TLS не применяются
+	Middleware []Middleware // обертки транспорта, первая получает запрос первой
+
+	Proxy     string // http://proxy:3128, пусто - HTTPS_PROXY/HTTP_PROXY/NO_PROXY из окружения
+	LocalAddr string // исходящий адрес соединений с Vault
+
+	// DialContext заменяет стандартный dialer для tcp соединений, LocalAddr при этом не применяется.
+	DialContext func(ctx context.Context, network, address string) (net.Conn, error)
+}
+
+// logf writes only to a caller's Logger: the library keeps quiet by default.
+func logf(logger Logger, format string, v ...interface{}) {
+	if logger == nil {
+		return
+	}
+	logger.Printf(format, v...)
 }
 
 func getBaseClientOptions() *ClientOptions {
@@ -23,6 +62,38 @@ func getBaseClientOptions() *ClientOptions {
 	}
 }
 
+func getClientOptions(options *ClientOptions) *ClientOptions {
+	if options == nil {
+		return getBaseClientOptions()
+	}
+
+	certPath := options.CertFilePath
+	if certPath != "" || !options.TLS.customCA() {
+		certPath = getCertFilePath(certPath)
+	}
+
+	return &ClientOptions{
+		TokenFilePath: getTokenFilePath(options.TokenFilePath),
+		CertFilePath:  certPath,
+		TLS:           options.TLS,
+		Token:         options.Token,
+		TokenStore:    options.TokenStore,
+		RevokeOnClose: options.RevokeOnClose,
+		Renewal:       options.Renewal,
+		Cache:         options.Cache,
+		Limit:         options.Limit,
+		Breaker:       options.Breaker,
+		MaxRetries:    options.MaxRetries,
+		RetryWait:     options.RetryWait,
+		Logger:        options.Logger,
+		HTTPClient:    options.HTTPClient,
+		Middleware:    options.Middleware,
+		Proxy:         options.Proxy,
+		LocalAddr:     options.LocalAddr,
+		DialContext:   options.DialContext,
+	}
+}
+
 func getTokenFilePath(data string) string {
 	var tokenPath string
 
@@ -1,7 +1,10 @@
 package vault
 
 import (
+	"bytes"
 	"github.com/stretchr/testify/assert"
+	"log"
+	"os"
 	"os/user"
 	"path/filepath"
 	"testing"
@@ -67,3 +70,17 @@ func TestGetBaseClientOptions(t *testing.T) {
 
 	assert.Equal(t, expect, getBaseClientOptions())
 }
+
+func TestLogf(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	logf(nil, "vault: %s", "warning")
+	assert.Empty(t, output.String())
+
+	logger := &testLogger{}
+	logf(logger, "vault: %s", "warning")
+	assert.Equal(t, []string{"vault: warning"}, logger.messages)
+	assert.Empty(t, output.String())
+}
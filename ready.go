@@ -0,0 +1,106 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	baseReadyMinBackoff = 500 * time.Millisecond
+	baseReadyMaxBackoff = 10 * time.Second
+)
+
+type ReadyStage string
+
+const (
+	ReadyStageHealth ReadyStage = "health"
+	ReadyStageUnseal ReadyStage = "unseal"
+	ReadyStageLogin  ReadyStage = "login"
+	ReadyStageDone   ReadyStage = "done"
+)
+
+type WaitOptions struct {
+	Timeout    time.Duration         // общее время ожидания, 0 - только ctx
+	MinBackoff time.Duration         // первая пауза между попытками (по умолчанию 500ms)
+	MaxBackoff time.Duration         // максимальная пауза между попытками (по умолчанию 10s)
+	Progress   func(p ReadyProgress) // вызывается после каждой попытки
+}
+
+type ReadyProgress struct {
+	Attempt int
+	Stage   ReadyStage // этап, на котором остановилась попытка
+	Health  *Health
+	Err     error
+	Wait    time.Duration // пауза до следующей попытки
+}
+
+var errVaultSealed = errors.New("vault is sealed or not initialized")
+
+// WaitReady blocks until Vault reports an unsealed node and the client is able
+// to log in, or until ctx or the timeout expires.
+func (c Client) WaitReady(ctx context.Context, options *WaitOptions) error {
+	if options == nil {
+		options = &WaitOptions{}
+	}
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	backoff := options.MinBackoff
+	if backoff <= 0 {
+		backoff = baseReadyMinBackoff
+	}
+	maxBackoff := options.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = baseReadyMaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		progress := c.readyAttempt(ctx)
+		progress.Attempt = attempt
+
+		if progress.Err == nil {
+			if options.Progress != nil {
+				options.Progress(progress)
+			}
+			return nil
+		}
+
+		progress.Wait = backoff
+		if options.Progress != nil {
+			options.Progress(progress)
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("vault is not ready (%s): %v", progress.Stage, progress.Err)
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (c Client) readyAttempt(ctx context.Context) ReadyProgress {
+	health, err := c.Health(ctx, &HealthOptions{StandbyOk: true, PerfStandbyOk: true})
+	if err != nil {
+		return ReadyProgress{Stage: ReadyStageHealth, Err: err}
+	}
+	if !health.Initialized || health.Sealed {
+		return ReadyProgress{Stage: ReadyStageUnseal, Health: health, Err: errVaultSealed}
+	}
+
+	if _, err := c.token(ctx); err != nil {
+		return ReadyProgress{Stage: ReadyStageLogin, Health: health, Err: err}
+	}
+	return ReadyProgress{Stage: ReadyStageDone, Health: health}
+}
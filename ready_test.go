@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientWaitReady(t *testing.T) {
+	var healthCalls, loginCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/sys/health":
+			switch atomic.AddInt32(&healthCalls, 1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte(`{"initialized":true,"sealed":true}`))
+			default:
+				_, _ = w.Write([]byte(`{"initialized":true,"sealed":false}`))
+			}
+		case "/v1/auth/approle/login":
+			if atomic.AddInt32(&loginCalls, 1) == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"test_token","lease_duration":3600}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+
+	var progress []ReadyProgress
+	err := client.WaitReady(context.Background(), &WaitOptions{
+		Timeout:    5 * time.Second,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+		Progress:   func(p ReadyProgress) { progress = append(progress, p) },
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(progress))
+	assert.Equal(t, []ReadyStage{ReadyStageUnseal, ReadyStageLogin, ReadyStageDone},
+		[]ReadyStage{progress[0].Stage, progress[1].Stage, progress[2].Stage})
+	assert.Equal(t, []time.Duration{time.Millisecond, 2 * time.Millisecond, 0},
+		[]time.Duration{progress[0].Wait, progress[1].Wait, progress[2].Wait})
+	assert.Equal(t, 3, progress[2].Attempt)
+	assert.Nil(t, progress[2].Err)
+}
+
+func TestClientWaitReadyTimeout(t *testing.T) {
+	var calls int32
+	server := newTestNodeServer(http.StatusServiceUnavailable, &calls)
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	err := client.WaitReady(context.Background(), &WaitOptions{Timeout: 50 * time.Millisecond, MinBackoff: 10 * time.Millisecond})
+
+	assert.Error(t, err, "")
+	assert.Contains(t, err.Error(), string(ReadyStageUnseal))
+	assert.True(t, atomic.LoadInt32(&calls) > 1)
+}
+
+func TestClientWaitReadyUnreachable(t *testing.T) {
+	var calls int32
+	server := newTestNodeServer(http.StatusOK, &calls)
+	server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client := newTestClient(t, server, nil)
+	err := client.WaitReady(ctx, nil)
+	assert.Error(t, err, "")
+	assert.Contains(t, err.Error(), string(ReadyStageHealth))
+}
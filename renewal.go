@@ -0,0 +1,87 @@
+package vault
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	baseRenewGracePeriod = 1500 * time.Second
+	baseRenewMinTtl      = 10 * time.Second
+)
+
+type RenewalPolicy struct {
+	GracePeriod   time.Duration // продлевать токен, если осталось меньше GracePeriod (по умолчанию 1500s)
+	GraceFraction float64       // продлевать токен, если осталось меньше доли от ttl последней выдачи или продления
+	MinTtl        time.Duration // авторизоваться заново, если осталось меньше MinTtl (по умолчанию 10s)
+	Increment     time.Duration // запрашиваемое продление для renew-self, 0 - ttl роли
+}
+
+type renewAction int
+
+const (
+	renewNone renewAction = iota
+	renewToken
+	renewReauth
+)
+
+func (r RenewalPolicy) action(info *TokenInfo, now time.Time) renewAction {
+	ttl := info.Ttl(now)
+	if ttl < 0 {
+		return renewNone
+	}
+
+	if ttl < r.minTtl() {
+		return renewReauth
+	}
+	if info.Renewable && ttl < r.threshold(info) {
+		return renewToken
+	}
+	return renewNone
+}
+
+// threshold is based on the TTL of the last login or renewal, not on the first
+// issue time, otherwise it would grow with every renewal.
+func (r RenewalPolicy) threshold(info *TokenInfo) time.Duration {
+	granted := info.grantedTtl()
+
+	if r.GraceFraction > 0 && granted > 0 {
+		return time.Duration(float64(granted) * r.GraceFraction)
+	}
+
+	grace := r.GracePeriod
+	if grace <= 0 {
+		grace = baseRenewGracePeriod
+		if granted > 0 && grace > granted/2 {
+			grace = granted / 2
+		}
+	}
+	return grace
+}
+
+// capped reports whether renew-self granted less than requested or than the
+// previous lease, i.e. the token is limited by its max TTL.
+func (r RenewalPolicy) capped(info, renewed *TokenInfo) bool {
+	granted := renewed.grantedTtl()
+	if r.Increment > 0 && granted < r.Increment {
+		return true
+	}
+	return info.LeaseDuration > 0 && renewed.LeaseDuration < info.LeaseDuration
+}
+
+func (r RenewalPolicy) minTtl() time.Duration {
+	if r.MinTtl > 0 {
+		return r.MinTtl
+	}
+	return baseRenewMinTtl
+}
+
+func (r RenewalPolicy) body() []byte {
+	data := map[string]string{}
+	if r.Increment > 0 {
+		data["increment"] = durationString(r.Increment)
+	}
+
+	body, _ := json.Marshal(data)
+	return body
+}
@@ -0,0 +1,88 @@
+package vault
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRenewalPolicyAction(t *testing.T) {
+	now := time.Now()
+	token := func(issued, left time.Duration, renewable bool) *TokenInfo {
+		info := &TokenInfo{ExpireTime: now.Add(left), Renewable: renewable}
+		if issued > 0 {
+			info.IssueTime = now.Add(-issued)
+		}
+		return info
+	}
+
+	testCases := []struct {
+		name   string
+		policy RenewalPolicy
+		info   *TokenInfo
+		expect renewAction
+	}{
+		{name: "unknownExpiry", info: &TokenInfo{Renewable: true}, expect: renewNone},
+		{name: "baseLongToken", info: token(time.Hour, 30*time.Minute, true), expect: renewNone},
+		{name: "baseLongTokenInGrace", info: token(time.Hour, 20*time.Minute, true), expect: renewToken},
+		{name: "baseShortToken", info: token(time.Minute, 4*time.Minute, true), expect: renewNone},
+		{name: "baseShortTokenInGrace", info: token(4*time.Minute, time.Minute, true), expect: renewToken},
+		{name: "baseNotRenewable", info: token(time.Hour, time.Minute, false), expect: renewNone},
+		{name: "baseNotRenewableExpiring", info: token(time.Hour, 5*time.Second, false), expect: renewReauth},
+		{name: "renewableExpiring", info: token(time.Hour, 5*time.Second, true), expect: renewReauth},
+		{
+			name:   "gracePeriod",
+			policy: RenewalPolicy{GracePeriod: time.Minute},
+			info:   token(4*time.Minute, 2*time.Minute, true),
+			expect: renewNone,
+		},
+		{
+			name:   "graceFraction",
+			policy: RenewalPolicy{GracePeriod: time.Minute, GraceFraction: 0.5},
+			info:   token(3*time.Minute, 2*time.Minute, true),
+			expect: renewToken,
+		},
+		{
+			name:   "graceFractionUnknownIssue",
+			policy: RenewalPolicy{GraceFraction: 0.5},
+			info:   token(0, 20*time.Minute, true),
+			expect: renewToken,
+		},
+		{
+			name:   "minTtl",
+			policy: RenewalPolicy{MinTtl: 2 * time.Minute},
+			info:   token(3*time.Minute, time.Minute, true),
+			expect: renewReauth,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.policy.action(tc.info, now))
+		})
+	}
+}
+
+func TestRenewalPolicyGraceFractionRenewed(t *testing.T) {
+	policy := RenewalPolicy{GraceFraction: 0.3}
+	now := time.Now()
+	info := &TokenInfo{IssueTime: now, ExpireTime: now.Add(5 * time.Minute), LeaseDuration: 300, Renewable: true}
+
+	// the token is renewed for 300s every 240s, the first issue time goes further into the past
+	for i := 0; i < 5; i++ {
+		now = now.Add(200 * time.Second)
+		assert.Equal(t, renewNone, policy.action(info, now))
+		assert.Equal(t, 90*time.Second, policy.threshold(info))
+
+		now = now.Add(40 * time.Second)
+		assert.Equal(t, renewToken, policy.action(info, now))
+
+		info = &TokenInfo{IssueTime: info.IssueTime, ExpireTime: now.Add(5 * time.Minute), LeaseDuration: 300, Renewable: true}
+		assert.Equal(t, renewNone, policy.action(info, now))
+	}
+}
+
+func TestRenewalPolicyBody(t *testing.T) {
+	assert.Equal(t, `{}`, string(RenewalPolicy{}.body()))
+	assert.Equal(t, `{"increment":"300s"}`, string(RenewalPolicy{Increment: 5 * time.Minute}.body()))
+}
@@ -0,0 +1,403 @@
+package vault
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"time"
+)
+
+type TLSOptions struct {
+	ClientCertFile string   // клиентский сертификат для mutual TLS
+	ClientKeyFile  string   // ключ клиентского сертификата
+	ServerName     string   // имя сервера для проверки сертификата (SNI)
+	MinVersion     uint16   // минимальная версия TLS, например tls.VersionTLS12
+	CipherSuites   []uint16 // разрешенные cipher suites
+	CAPath         string   // каталог с CA сертификатами
+	UseSystemRoots bool     // добавить системные CA к CertFilePath и CAPath
+
+	// InsecureSkipVerify отключает проверку сертификата Vault.
+	// Только для локальной разработки.
+	InsecureSkipVerify bool
+
+	ReloadInterval time.Duration // период проверки файлов сертификатов, 0 - без перезагрузки
+	OnReloadError  func(error)   // вызывается, если новые файлы сертификатов некорректны
+}
+
+// customCA reports whether the default CA file is not needed: other roots are
+// configured or the certificate is not verified at all.
+func (t TLSOptions) customCA() bool {
+	return t.CAPath != "" || t.UseSystemRoots || t.InsecureSkipVerify
+}
+
+func newTLSConfig(options *ClientOptions) (*tls.Config, error) {
+	certPool, err := newCertPool(options)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{RootCAs: certPool}
+	tlsOptions := options.TLS
+
+	if tlsOptions.ClientCertFile != "" || tlsOptions.ClientKeyFile != "" {
+		if tlsOptions.ClientCertFile == "" || tlsOptions.ClientKeyFile == "" {
+			return nil, errors.New("both client certificate and key files are required")
+		}
+
+		certificate, err := tls.LoadX509KeyPair(tlsOptions.ClientCertFile, tlsOptions.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate %s: %v", tlsOptions.ClientCertFile, err)
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	config.ServerName = tlsOptions.ServerName
+	config.MinVersion = tlsOptions.MinVersion
+	config.CipherSuites = tlsOptions.CipherSuites
+	config.InsecureSkipVerify = tlsOptions.InsecureSkipVerify
+
+	return config, nil
+}
+
+func newCertPool(options *ClientOptions) (*x509.CertPool, error) {
+	certPool := x509.NewCertPool()
+	if options.TLS.UseSystemRoots {
+		systemPool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+		certPool = systemPool
+	}
+
+	if options.CertFilePath != "" {
+		if err := appendCertFile(certPool, options.CertFilePath, options.Logger); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.TLS.CAPath != "" {
+		files, err := ioutil.ReadDir(options.TLS.CAPath)
+		if err != nil {
+			return nil, err
+		}
+
+		loaded := 0
+		for _, file := range files {
+			if file.IsDir() {
+				continue
+			}
+			if err := appendCertFile(certPool, filepath.Join(options.TLS.CAPath, file.Name()), options.Logger); err != nil {
+				logf(options.Logger, "vault: skip CA file: %v", err)
+				continue
+			}
+			loaded++
+		}
+		if loaded == 0 {
+			return nil, fmt.Errorf("no certificates found in CA path %s", options.TLS.CAPath)
+		}
+	}
+
+	return certPool, nil
+}
+
+func appendCertFile(certPool *x509.CertPool, path string, logger Logger) error {
+	certificates, err := loadCertificates(path)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, certificate := range certificates {
+		switch {
+		case now.After(certificate.NotAfter):
+			logf(logger, "vault: CA certificate %q from %s expired at %s", certificate.Subject.CommonName, path, certificate.NotAfter)
+		case now.Before(certificate.NotBefore):
+			logf(logger, "vault: CA certificate %q from %s is not valid before %s", certificate.Subject.CommonName, path, certificate.NotBefore)
+		}
+		certPool.AddCert(certificate)
+	}
+	return nil
+}
+
+// loadCertificates reads a PEM bundle or a DER encoded file.
+func loadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA file %s: %v", path, err)
+	}
+
+	var certificates []*x509.Certificate
+	if bytes.Contains(data, []byte("-----BEGIN")) {
+		for {
+			var block *pem.Block
+			block, data = pem.Decode(data)
+			if block == nil {
+				break
+			}
+			if block.Type != "CERTIFICATE" {
+				continue
+			}
+
+			certificate, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("parse CA file %s: %v", path, err)
+			}
+			certificates = append(certificates, certificate)
+		}
+	} else {
+		certificates, err = x509.ParseCertificates(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse CA file %s: %v", path, err)
+		}
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("no certificates found in CA file %s", path)
+	}
+	return certificates, nil
+}
+
+type TLSCertificateInfo struct {
+	Subject   string
+	Issuer    string
+	NotBefore time.Time
+	NotAfter  time.Time
+	DNSNames  []string
+}
+
+type TLSReport struct {
+	Address     string
+	ServerName  string
+	Version     uint16
+	CipherSuite uint16
+	Peer        []TLSCertificateInfo   // цепочка, которую прислал сервер
+	Chains      [][]TLSCertificateInfo // цепочки до доверенных CA
+	VerifyError error                  // nil, если сертификат сервера прошел проверку
+}
+
+// VerifyTLS connects to the first Vault address, performs a TLS handshake
+// with the client settings and describes the certificate chain. A chain
+// that fails verification is reported in TLSReport.VerifyError.
+func (c Client) VerifyTLS(ctx context.Context) (*TLSReport, error) {
+	address := c.endpoints.candidates(false)[0].address
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("vault address %s does not use TLS", address)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	config, err := c.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	serverName := config.ServerName
+	if serverName == "" {
+		serverName = u.Hostname()
+	}
+	rootCAs := config.RootCAs
+	config.ServerName = serverName
+	config.InsecureSkipVerify = true
+
+	transport, err := c.dialTransport()
+	if err != nil {
+		return nil, err
+	}
+	rawConn, err := dialTLSHost(ctx, transport, host)
+	if err != nil {
+		return nil, err
+	}
+	defer rawConn.Close()
+
+	conn := tls.Client(rawConn, config)
+	if err := conn.Handshake(); err != nil {
+		return nil, fmt.Errorf("tls handshake with %s: %v", host, err)
+	}
+	state := conn.ConnectionState()
+
+	report := &TLSReport{
+		Address:     address,
+		ServerName:  serverName,
+		Version:     state.Version,
+		CipherSuite: state.CipherSuite,
+		Peer:        certificateInfos(state.PeerCertificates),
+	}
+	if len(state.PeerCertificates) == 0 {
+		report.VerifyError = errors.New("server sent no certificates")
+		return report, nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, certificate := range state.PeerCertificates[1:] {
+		intermediates.AddCert(certificate)
+	}
+	chains, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         rootCAs,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+	})
+	report.VerifyError = err
+	for _, chain := range chains {
+		report.Chains = append(report.Chains, certificateInfos(chain))
+	}
+	return report, nil
+}
+
+// tlsConfig returns a copy of the TLS settings requests are sent with.
+func (c Client) tlsConfig() (*tls.Config, error) {
+	if c.options.HTTPClient == nil {
+		return newTLSConfig(c.options)
+	}
+
+	transport, ok := c.options.HTTPClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	if transport.TLSClientConfig == nil {
+		return &tls.Config{}, nil
+	}
+	return transport.TLSClientConfig.Clone(), nil
+}
+
+// dialTransport returns the transport whose proxy and dialer are used by real requests.
+func (c Client) dialTransport() (*http.Transport, error) {
+	if c.options.HTTPClient == nil {
+		return newTransport(c.options)
+	}
+
+	transport, ok := c.options.HTTPClient.Transport.(*http.Transport)
+	if !ok || transport == nil {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	return transport, nil
+}
+
+// dialTLSHost opens a tcp connection to host the way transport does:
+// through its dialer and, if the proxy applies, a CONNECT tunnel.
+func dialTLSHost(ctx context.Context, transport *http.Transport, host string) (net.Conn, error) {
+	dialContext := transport.DialContext
+	if dialContext == nil {
+		dialContext = (&net.Dialer{}).DialContext
+	}
+
+	var proxyUrl *url.URL
+	if transport.Proxy != nil {
+		request, err := http.NewRequest("GET", "https://"+host, nil)
+		if err != nil {
+			return nil, err
+		}
+		if proxyUrl, err = transport.Proxy(request); err != nil {
+			return nil, err
+		}
+	}
+	if proxyUrl == nil {
+		conn, err := dialContext(ctx, "tcp", host)
+		if err != nil {
+			return nil, err
+		}
+		setDeadline(ctx, conn)
+		return conn, nil
+	}
+
+	proxyHost := proxyUrl.Host
+	if proxyUrl.Port() == "" {
+		switch proxyUrl.Scheme {
+		case "http":
+			proxyHost = net.JoinHostPort(proxyUrl.Hostname(), "80")
+		case "https":
+			proxyHost = net.JoinHostPort(proxyUrl.Hostname(), "443")
+		}
+	}
+	conn, err := dialContext(ctx, "tcp", proxyHost)
+	if err != nil {
+		return nil, err
+	}
+	setDeadline(ctx, conn)
+
+	switch proxyUrl.Scheme {
+	case "http":
+	case "https":
+		conn = tls.Client(conn, &tls.Config{ServerName: proxyUrl.Hostname()})
+	default:
+		_ = conn.Close()
+		return nil, fmt.Errorf("proxy %s: unsupported scheme %q", proxyUrl.Host, proxyUrl.Scheme)
+	}
+
+	if err := connectProxy(conn, transport.ProxyConnectHeader, proxyUrl, host); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connectProxy asks the proxy to open a tunnel to host.
+func connectProxy(conn net.Conn, header http.Header, proxyUrl *url.URL, host string) error {
+	if header == nil {
+		header = http.Header{}
+	} else {
+		header = header.Clone()
+	}
+	if user := proxyUrl.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: host},
+		Host:   host,
+		Header: header,
+	}
+	if err := request.Write(conn); err != nil {
+		return fmt.Errorf("proxy CONNECT to %s: %v", host, err)
+	}
+
+	// the server speaks only after ClientHello, so nothing is buffered past the response
+	response, err := http.ReadResponse(bufio.NewReader(conn), request)
+	if err != nil {
+		return fmt.Errorf("proxy CONNECT to %s: %v", host, err)
+	}
+	_ = response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy CONNECT to %s: %s", host, response.Status)
+	}
+	return nil
+}
+
+func setDeadline(ctx context.Context, conn net.Conn) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+}
+
+func certificateInfos(certificates []*x509.Certificate) []TLSCertificateInfo {
+	infos := make([]TLSCertificateInfo, 0, len(certificates))
+	for _, certificate := range certificates {
+		infos = append(infos, TLSCertificateInfo{
+			Subject:   certificate.Subject.String(),
+			Issuer:    certificate.Issuer.String(),
+			NotBefore: certificate.NotBefore,
+			NotAfter:  certificate.NotAfter,
+			DNSNames:  certificate.DNSNames,
+		})
+	}
+	return infos
+}
@@ -0,0 +1,173 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// reloadingTransport sends requests through the current transport and
+// replaces it when the watched certificate files change. Connections that
+// are already open keep their TLS settings, new connections use the new ones.
+type reloadingTransport struct {
+	current atomic.Value
+
+	options  *ClientOptions
+	interval time.Duration
+	onError  func(error)
+
+	modTimes    map[string]time.Time
+	fingerprint [sha256.Size]byte
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newReloadingTransport(options *ClientOptions, transport *http.Transport) *reloadingTransport {
+	r := &reloadingTransport{
+		options:  options,
+		interval: options.TLS.ReloadInterval,
+		onError:  options.TLS.OnReloadError,
+		stop:     make(chan struct{}),
+	}
+	r.current.Store(transport)
+	r.modTimes, r.fingerprint, _ = r.snapshot()
+
+	go r.watch()
+	return r
+}
+
+func (r *reloadingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	return r.transport().RoundTrip(request)
+}
+
+func (r *reloadingTransport) CloseIdleConnections() {
+	r.transport().CloseIdleConnections()
+}
+
+func (r *reloadingTransport) transport() *http.Transport {
+	return r.current.Load().(*http.Transport)
+}
+
+func (r *reloadingTransport) close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+func (r *reloadingTransport) watch() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+func (r *reloadingTransport) reload() {
+	if !r.modified() {
+		return
+	}
+
+	modTimes, fingerprint, err := r.snapshot()
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if fingerprint == r.fingerprint {
+		r.modTimes = modTimes
+		return
+	}
+
+	tlsConfig, err := newTLSConfig(r.options)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+
+	old := r.transport()
+	transport := old.Clone()
+	transport.TLSClientConfig = tlsConfig
+	r.current.Store(transport)
+	old.CloseIdleConnections()
+
+	r.modTimes, r.fingerprint = modTimes, fingerprint
+}
+
+func (r *reloadingTransport) fail(err error) {
+	if r.onError != nil {
+		r.onError(err)
+	}
+}
+
+func (r *reloadingTransport) modified() bool {
+	files := r.files()
+	if len(files) != len(r.modTimes) {
+		return true
+	}
+
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return true
+		}
+		if modTime, ok := r.modTimes[file]; !ok || !modTime.Equal(info.ModTime()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *reloadingTransport) snapshot() (map[string]time.Time, [sha256.Size]byte, error) {
+	modTimes := map[string]time.Time{}
+	hash := sha256.New()
+
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, [sha256.Size]byte{}, err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, [sha256.Size]byte{}, err
+		}
+
+		modTimes[file] = info.ModTime()
+		hash.Write([]byte(file))
+		hash.Write(data)
+	}
+
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], hash.Sum(nil))
+	return modTimes, fingerprint, nil
+}
+
+func (r *reloadingTransport) files() []string {
+	var files []string
+	for _, file := range []string{r.options.CertFilePath, r.options.TLS.ClientCertFile, r.options.TLS.ClientKeyFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	if r.options.TLS.CAPath != "" {
+		entries, _ := ioutil.ReadDir(r.options.TLS.CAPath)
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				files = append(files, filepath.Join(r.options.TLS.CAPath, entry.Name()))
+			}
+		}
+	}
+
+	sort.Strings(files)
+	return files
+}
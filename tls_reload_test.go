@@ -0,0 +1,118 @@
+package vault
+
+import (
+	"context"
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func rewriteTestFile(t *testing.T, path string, data []byte) {
+	assert.Nil(t, ioutil.WriteFile(path, data, 0600))
+	modTime := time.Now().Add(time.Minute)
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestReloadingTransportCA(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	oldCA, newCA := newTestCA(t, "old"), newTestCA(t, "new")
+	caFile := writeTestFile(t, dir, "ca.pem", oldCA.certPEM)
+
+	server := newTestTLSServer(t, newCA, nil)
+	defer server.Close()
+
+	options := &ClientOptions{CertFilePath: caFile, TLS: TLSOptions{ReloadInterval: time.Hour}}
+	actions, err := newActions(options)
+	assert.Nil(t, err)
+	defer actions.close()
+
+	transport := actions.httpClient.Transport.(*reloadingTransport)
+	_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+	assert.Error(t, err, "")
+
+	rewriteTestFile(t, caFile, newCA.certPEM)
+	transport.reload()
+
+	_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+	assert.Nil(t, err)
+}
+
+func TestReloadingTransportInvalidFiles(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	client := newTestLeaf(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+	certFile := writeTestFile(t, dir, "client.pem", client.certPEM)
+	keyFile := writeTestFile(t, dir, "client.key", client.keyPEM)
+
+	server := newTestTLSServer(t, ca, ca)
+	defer server.Close()
+
+	var errors []error
+	options := &ClientOptions{CertFilePath: caFile, TLS: TLSOptions{
+		ClientCertFile: certFile,
+		ClientKeyFile:  keyFile,
+		ReloadInterval: time.Hour,
+		OnReloadError:  func(err error) { errors = append(errors, err) },
+	}}
+	actions, err := newActions(options)
+	assert.Nil(t, err)
+	defer actions.close()
+
+	transport := actions.httpClient.Transport.(*reloadingTransport)
+	current := transport.transport()
+
+	rewriteTestFile(t, keyFile, []byte("broken key"))
+	transport.reload()
+	assert.Equal(t, 1, len(errors))
+	assert.Equal(t, current, transport.transport())
+
+	_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+	assert.Nil(t, err)
+
+	_ = os.Remove(certFile)
+	transport.reload()
+	assert.Equal(t, 2, len(errors))
+	assert.Equal(t, current, transport.transport())
+}
+
+func TestReloadingTransportUnchanged(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+
+	actions, _ := newActions(&ClientOptions{CertFilePath: caFile, TLS: TLSOptions{ReloadInterval: time.Hour}})
+	defer actions.close()
+
+	transport := actions.httpClient.Transport.(*reloadingTransport)
+	current := transport.transport()
+
+	rewriteTestFile(t, caFile, ca.certPEM)
+	transport.reload()
+	assert.Equal(t, current, transport.transport())
+}
+
+func TestReloadingTransportWatch(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	caFile := writeTestFile(t, dir, "ca.pem", newTestCA(t, "old").certPEM)
+
+	actions, _ := newActions(&ClientOptions{CertFilePath: caFile, TLS: TLSOptions{ReloadInterval: 10 * time.Millisecond}})
+	defer actions.close()
+
+	transport := actions.httpClient.Transport.(*reloadingTransport)
+	current := transport.transport()
+	rewriteTestFile(t, caFile, newTestCA(t, "new").certPEM)
+
+	assert.Eventually(t, func() bool { return transport.transport() != current }, time.Second, 10*time.Millisecond)
+}
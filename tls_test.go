@@ -0,0 +1,387 @@
+package vault
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCertificate(t *testing.T, name string, parent *testCertificate, template *x509.Certificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template.SerialNumber = serial
+	template.Subject = pkix.Name{CommonName: name}
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	cert, _ := x509.ParseCertificate(der)
+	keyDer, _ := x509.MarshalECPrivateKey(key)
+
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}
+}
+
+func newTestCA(t *testing.T, name string) *testCertificate {
+	return newTestCertificate(t, name, nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	})
+}
+
+func newTestLeaf(t *testing.T, name string, ca *testCertificate, usage x509.ExtKeyUsage) *testCertificate {
+	return newTestCertificate(t, name, ca, &x509.Certificate{
+		DNSNames:    []string{name},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{usage},
+	})
+}
+
+func (c *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	certificate, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	assert.Nil(t, err)
+	return certificate
+}
+
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	assert.Nil(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func newTestTLSServer(t *testing.T, ca *testCertificate, clientCA *testCertificate) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{newTestLeaf(t, "vault.test", ca, x509.ExtKeyUsageServerAuth).tlsCertificate(t)},
+	}
+	if clientCA != nil {
+		pool := x509.NewCertPool()
+		pool.AddCert(clientCA.cert)
+		server.TLS.ClientCAs = pool
+		server.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	server.StartTLS()
+	return server
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	client := newTestLeaf(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+	certFile := writeTestFile(t, dir, "client.pem", client.certPEM)
+	keyFile := writeTestFile(t, dir, "client.key", client.keyPEM)
+
+	config, err := newTLSConfig(&ClientOptions{
+		CertFilePath: caFile,
+		TLS: TLSOptions{
+			ClientCertFile: certFile,
+			ClientKeyFile:  keyFile,
+			ServerName:     "vault.test",
+			MinVersion:     tls.VersionTLS12,
+			CipherSuites:   []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		},
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(config.Certificates))
+	assert.Equal(t, "vault.test", config.ServerName)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256}, config.CipherSuites)
+	assert.False(t, config.InsecureSkipVerify)
+}
+
+func TestNewTLSConfigNegative(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+	keyFile := writeTestFile(t, dir, "ca.key", newTestCA(t, "other").keyPEM)
+
+	testCases := []struct {
+		name    string
+		options TLSOptions
+	}{
+		{name: "certWithoutKey", options: TLSOptions{ClientCertFile: caFile}},
+		{name: "keyWithoutCert", options: TLSOptions{ClientKeyFile: keyFile}},
+		{name: "mismatchedKey", options: TLSOptions{ClientCertFile: caFile, ClientKeyFile: keyFile}},
+		{name: "caPathNotExist", options: TLSOptions{CAPath: filepath.Join(dir, "not_exist")}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newTLSConfig(&ClientOptions{CertFilePath: caFile, TLS: tc.options})
+			assert.Error(t, err, "")
+		})
+	}
+}
+
+func TestNewCertPoolCAPath(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	first, second := newTestCA(t, "first"), newTestCA(t, "second")
+	writeTestFile(t, dir, "first.pem", first.certPEM)
+	writeTestFile(t, dir, "second.pem", second.certPEM)
+	_ = os.Mkdir(filepath.Join(dir, "nested"), 0700)
+
+	pool, err := newCertPool(&ClientOptions{TLS: TLSOptions{CAPath: dir}})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(pool.Subjects()))
+}
+
+func TestGetClientOptionsCustomCA(t *testing.T) {
+	assert.Equal(t, "", getClientOptions(&ClientOptions{TLS: TLSOptions{CAPath: "/etc/ssl/certs"}}).CertFilePath)
+	assert.Equal(t, "", getClientOptions(&ClientOptions{TLS: TLSOptions{UseSystemRoots: true}}).CertFilePath)
+	assert.Equal(t, "/tmp/ca.pem", getClientOptions(&ClientOptions{CertFilePath: "/tmp/ca.pem", TLS: TLSOptions{UseSystemRoots: true}}).CertFilePath)
+	assert.Equal(t, baseCertFile, getClientOptions(&ClientOptions{}).CertFilePath)
+}
+
+func TestActionsMutualTLS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	client := newTestLeaf(t, "client", ca, x509.ExtKeyUsageClientAuth)
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+	certFile := writeTestFile(t, dir, "client.pem", client.certPEM)
+	keyFile := writeTestFile(t, dir, "client.key", client.keyPEM)
+
+	server := newTestTLSServer(t, ca, ca)
+	defer server.Close()
+
+	testCases := []struct {
+		name    string
+		options *ClientOptions
+		err     bool
+	}{
+		{
+			name:    "clientCertificate",
+			options: &ClientOptions{CertFilePath: caFile, TLS: TLSOptions{ClientCertFile: certFile, ClientKeyFile: keyFile}},
+		},
+		{
+			name:    "noClientCertificate",
+			options: &ClientOptions{CertFilePath: caFile},
+			err:     true,
+		},
+		{
+			name:    "unknownServerCA",
+			options: &ClientOptions{CertFilePath: writeTestFile(t, dir, "other.pem", newTestCA(t, "other").certPEM), TLS: TLSOptions{ClientCertFile: certFile, ClientKeyFile: keyFile}},
+			err:     true,
+		},
+		{
+			name:    "insecureSkipVerify",
+			options: getClientOptions(&ClientOptions{TLS: TLSOptions{InsecureSkipVerify: true, ClientCertFile: certFile, ClientKeyFile: keyFile}}),
+		},
+		{
+			name:    "wrongServerName",
+			options: &ClientOptions{CertFilePath: caFile, TLS: TLSOptions{ServerName: "other.test", ClientCertFile: certFile, ClientKeyFile: keyFile}},
+			err:     true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actions, err := newActions(tc.options)
+			assert.Nil(t, err)
+
+			_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+			assert.Equal(t, tc.err, err != nil)
+		})
+	}
+}
+
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, v ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestLoadCertificates(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	first, second := newTestCA(t, "first"), newTestCA(t, "second")
+	bundle := append(append([]byte{}, first.certPEM...), second.certPEM...)
+
+	testCases := []struct {
+		name   string
+		path   string
+		expect int
+		err    string
+	}{
+		{name: "pem", path: writeTestFile(t, dir, "first.pem", first.certPEM), expect: 1},
+		{name: "pemBundle", path: writeTestFile(t, dir, "bundle.pem", bundle), expect: 2},
+		{name: "der", path: writeTestFile(t, dir, "first.der", first.cert.Raw), expect: 1},
+		{name: "empty", path: writeTestFile(t, dir, "empty.pem", nil), err: "no certificates found in CA file"},
+		{name: "keyOnly", path: writeTestFile(t, dir, "key.pem", first.keyPEM), err: "no certificates found in CA file"},
+		{name: "garbage", path: writeTestFile(t, dir, "garbage.pem", []byte("garbage")), err: "parse CA file"},
+		{name: "notExist", path: filepath.Join(dir, "not_exist.pem"), err: "read CA file"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			certificates, err := loadCertificates(tc.path)
+			if tc.err != "" {
+				assert.Error(t, err, "")
+				assert.Contains(t, err.Error(), tc.err)
+				assert.Contains(t, err.Error(), tc.path)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, len(certificates))
+		})
+	}
+}
+
+func TestNewCertPoolWarnings(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	expired := newTestCertificate(t, "expired", nil, &x509.Certificate{
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		NotBefore:             time.Now().Add(-2 * time.Hour),
+		NotAfter:              time.Now().Add(-time.Hour),
+	})
+	writeTestFile(t, dir, "expired.pem", expired.certPEM)
+	writeTestFile(t, dir, "garbage.pem", []byte("garbage"))
+
+	logger := &testLogger{}
+	pool, err := newCertPool(&ClientOptions{Logger: logger, TLS: TLSOptions{CAPath: dir}})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(pool.Subjects()))
+	assert.Equal(t, 2, len(logger.messages))
+	assert.Contains(t, logger.messages[0], "expired")
+	assert.Contains(t, logger.messages[1], "garbage.pem")
+
+	empty, _ := ioutil.TempDir(dir, "")
+	_, err = newCertPool(&ClientOptions{Logger: logger, TLS: TLSOptions{CAPath: empty}})
+	assert.Error(t, err, "")
+}
+
+func TestVerifyTLS(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	server := newTestTLSServer(t, ca, nil)
+	defer server.Close()
+
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+	client := newTestClient(t, server, &ClientOptions{CertFilePath: caFile})
+
+	report, err := client.VerifyTLS(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, report.VerifyError)
+	assert.Equal(t, "127.0.0.1", report.ServerName)
+	assert.Equal(t, "CN=vault.test", report.Peer[0].Subject)
+	assert.Equal(t, "CN=ca", report.Peer[0].Issuer)
+	assert.Equal(t, 1, len(report.Chains))
+	assert.Equal(t, "CN=ca", report.Chains[0][1].Subject)
+
+	otherFile := writeTestFile(t, dir, "other.pem", newTestCA(t, "other").certPEM)
+	client = newTestClient(t, server, &ClientOptions{CertFilePath: otherFile})
+
+	report, err = client.VerifyTLS(context.Background())
+	assert.Nil(t, err)
+	assert.Error(t, report.VerifyError, "")
+	assert.Equal(t, 0, len(report.Chains))
+
+	plain := newTestVaultServer(t, nil)
+	defer plain.Close()
+
+	_, err = newTestClient(t, plain, &ClientOptions{CertFilePath: caFile}).VerifyTLS(context.Background())
+	assert.Error(t, err, "")
+}
+
+func TestVerifyTLSDialSettings(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	server := newTestTLSServer(t, ca, nil)
+	defer server.Close()
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+
+	var requests []string
+	proxyServer := newTestProxy(t, &requests)
+	defer proxyServer.Close()
+
+	client := newTestClient(t, server, &ClientOptions{CertFilePath: caFile, Proxy: proxyServer.URL})
+	report, err := client.VerifyTLS(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, report.VerifyError)
+	assert.Equal(t, []string{"CONNECT " + strings.TrimPrefix(server.URL, "https://")}, requests)
+
+	var dialed []string
+	client = newTestClient(t, server, &ClientOptions{
+		CertFilePath: caFile,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			dialed = append(dialed, address)
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	})
+	report, err = client.VerifyTLS(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, report.VerifyError)
+	assert.Equal(t, []string{strings.TrimPrefix(server.URL, "https://")}, dialed)
+
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer refusing.Close()
+
+	client = newTestClient(t, server, &ClientOptions{CertFilePath: caFile, Proxy: refusing.URL})
+	_, err = client.VerifyTLS(context.Background())
+	assert.EqualError(t, err, "proxy CONNECT to "+strings.TrimPrefix(server.URL, "https://")+": 403 Forbidden")
+}
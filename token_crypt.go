@@ -0,0 +1,244 @@
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	sealMagic      = "VTE1"
+	sealSaltSize   = 16
+	sealKeySize    = 32
+	sealIterations = 100000
+)
+
+var (
+	ErrTokenDecrypt = errors.New("vault token file cannot be decrypted")
+
+	machineIdFiles = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+)
+
+type EncryptedTokenStore struct {
+	Path string
+
+	secret []byte
+
+	mu   sync.Mutex
+	salt []byte
+	key  []byte
+}
+
+func NewEncryptedTokenStore(path string, secret []byte) (*EncryptedTokenStore, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("empty secret for encrypted token store")
+	}
+
+	return &EncryptedTokenStore{Path: getTokenFilePath(path), secret: secret}, nil
+}
+
+func PassphraseSecret(passphrase string) []byte {
+	return []byte(passphrase)
+}
+
+func KeyFileSecret(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file %s: %v", path, err)
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return b, nil
+}
+
+func MachineSecret() ([]byte, error) {
+	for _, path := range machineIdFiles {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		id := strings.TrimSpace(string(b))
+		if id == "" {
+			continue
+		}
+
+		secret := id
+		if usr, err := user.Current(); err == nil {
+			secret += ":" + usr.Uid
+		}
+		return []byte(secret), nil
+	}
+	return nil, errors.New("machine id not found")
+}
+
+func (e *EncryptedTokenStore) Load() (*TokenInfo, error) {
+	b, err := ioutil.ReadFile(e.Path)
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := e.open(b)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTokenInfo(plaintext)
+}
+
+func (e *EncryptedTokenStore) Save(info *TokenInfo) error {
+	plaintext, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	b, err := e.seal(plaintext)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(e.Path, b, 0600)
+}
+
+func (e *EncryptedTokenStore) Delete() error {
+	err := os.Remove(e.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (e *EncryptedTokenStore) Lock() (func(), error) {
+	return lockFile(e.Path + ".lock")
+}
+
+func (e *EncryptedTokenStore) seal(plaintext []byte) ([]byte, error) {
+	salt := make([]byte, sealSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+
+	aead, err := e.aead(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte(sealMagic), salt...)
+	out = append(out, nonce...)
+	return aead.Seal(out, nonce, plaintext, []byte(sealMagic)), nil
+}
+
+func (e *EncryptedTokenStore) open(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(sealMagic)) || len(data) < len(sealMagic)+sealSaltSize {
+		return nil, ErrTokenDecrypt
+	}
+	data = data[len(sealMagic):]
+
+	aead, err := e.aead(data[:sealSaltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[sealSaltSize:]
+
+	if len(data) < aead.NonceSize() {
+		return nil, ErrTokenDecrypt
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], []byte(sealMagic))
+	if err != nil {
+		return nil, ErrTokenDecrypt
+	}
+	return plaintext, nil
+}
+
+func (e *EncryptedTokenStore) aead(salt []byte) (cipher.AEAD, error) {
+	e.mu.Lock()
+	if !bytes.Equal(e.salt, salt) {
+		e.key = pbkdf2(sha256.New, e.secret, salt, sealIterations, sealKeySize)
+		e.salt = append([]byte(nil), salt...)
+	}
+	key := e.key
+	e.mu.Unlock()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func pbkdf2(h func() hash.Hash, password, salt []byte, iter, keyLen int) []byte {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	blocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, blocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= blocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:4])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = u[:0]
+			u = prf.Sum(u)
+			for x := range u {
+				t[x] ^= u[x]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
+
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
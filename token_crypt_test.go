@@ -0,0 +1,115 @@
+package vault
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPbkdf2(t *testing.T) {
+	testCases := []struct {
+		name   string
+		iter   int
+		expect string
+	}{
+		{name: "oneIteration", iter: 1, expect: "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{name: "twoIterations", iter: 2, expect: "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			key := pbkdf2(sha256.New, []byte("password"), []byte("salt"), tc.iter, 32)
+			assert.Equal(t, tc.expect, hex.EncodeToString(key))
+		})
+	}
+}
+
+func TestEncryptedTokenStorePositive1(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".token")
+	store, err := NewEncryptedTokenStore(path, PassphraseSecret("passphrase"))
+	assert.Nil(t, err)
+
+	_, err = store.Load()
+	assert.Equal(t, ErrTokenNotFound, err)
+
+	assert.Nil(t, store.Save(&TokenInfo{Token: "test_token"}))
+	info, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "test_token", info.Token)
+
+	stat, _ := os.Stat(path)
+	assert.Equal(t, os.FileMode(0600), stat.Mode().Perm())
+	raw, _ := ioutil.ReadFile(path)
+	assert.NotContains(t, string(raw), "test_token")
+
+	assert.Nil(t, store.Delete())
+	_, err = store.Load()
+	assert.Equal(t, ErrTokenNotFound, err)
+}
+
+func TestEncryptedTokenStoreNegative1(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".token")
+	store, _ := NewEncryptedTokenStore(path, PassphraseSecret("passphrase"))
+	other, _ := NewEncryptedTokenStore(path, PassphraseSecret("other"))
+	assert.Nil(t, store.Save(&TokenInfo{Token: "test_token"}))
+
+	_, err := other.Load()
+	assert.Equal(t, ErrTokenDecrypt, err)
+
+	_ = ioutil.WriteFile(path, []byte("plain_token"), 0600)
+	_, err = store.Load()
+	assert.Equal(t, ErrTokenDecrypt, err)
+
+	_, err = NewEncryptedTokenStore(path, nil)
+	assert.Error(t, err, "")
+}
+
+func TestKeyFileSecret(t *testing.T) {
+	file, _ := ioutil.TempFile("", "")
+	defer os.Remove(file.Name())
+
+	_, err := KeyFileSecret(file.Name())
+	assert.Error(t, err, "")
+
+	_ = ioutil.WriteFile(file.Name(), []byte("secret"), 0600)
+	secret, err := KeyFileSecret(file.Name())
+	assert.Nil(t, err)
+	assert.Equal(t, []byte("secret"), secret)
+
+	_, err = KeyFileSecret("path_not_exist/key")
+	assert.Error(t, err, "")
+}
+
+func TestClientEncryptedTokenStoreReauth(t *testing.T) {
+	server := newTestVaultServer(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "test_token", req.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	defer server.Close()
+
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".token")
+	_ = ioutil.WriteFile(path, []byte("garbage"), 0600)
+	store, _ := NewEncryptedTokenStore(path, PassphraseSecret("passphrase"))
+	client := newTestClient(t, server, &ClientOptions{TokenStore: store})
+
+	_, err := client.Get("secret/path")
+	assert.Nil(t, err)
+
+	info, err := store.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "test_token", info.Token)
+}
@@ -0,0 +1,174 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrTokenNotFound = errors.New("vault token not found")
+
+type TokenInfo struct {
+	Token         string    `json:"token"`
+	Accessor      string    `json:"accessor,omitempty"`
+	IssueTime     time.Time `json:"issue_time"`
+	ExpireTime    time.Time `json:"expire_time"`
+	LeaseDuration int       `json:"lease_duration,omitempty"`
+	Renewable     bool      `json:"renewable"`
+	Policies      []string  `json:"policies,omitempty"`
+	AuthMethod    string    `json:"auth_method,omitempty"`
+	Role          string    `json:"role,omitempty"`
+	Address       string    `json:"address,omitempty"`
+}
+
+// Ttl returns the remaining lifetime of the token, -1 if its expiry is unknown.
+func (t TokenInfo) Ttl(now time.Time) time.Duration {
+	if t.ExpireTime.IsZero() {
+		return -1
+	}
+	return t.ExpireTime.Sub(now)
+}
+
+// grantedTtl returns the TTL granted by the last login or renewal, 0 if unknown.
+func (t TokenInfo) grantedTtl() time.Duration {
+	if t.LeaseDuration > 0 {
+		return time.Duration(t.LeaseDuration) * time.Second
+	}
+	if !t.IssueTime.IsZero() && !t.ExpireTime.IsZero() {
+		return t.ExpireTime.Sub(t.IssueTime)
+	}
+	return 0
+}
+
+type TokenStore interface {
+	Load() (*TokenInfo, error)
+	Save(info *TokenInfo) error
+	Delete() error
+}
+
+// TokenLocker is implemented by stores shared between processes.
+// The client holds the lock while it checks and refreshes the token.
+type TokenLocker interface {
+	Lock() (unlock func(), err error)
+}
+
+type FileTokenStore struct {
+	Path string
+}
+
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{Path: getTokenFilePath(path)}
+}
+
+func (f *FileTokenStore) Load() (*TokenInfo, error) {
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return nil, ErrTokenNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeTokenInfo(b)
+}
+
+func (f *FileTokenStore) Save(info *TokenInfo) error {
+	b, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(f.Path, b, 0600)
+}
+
+func (f *FileTokenStore) Delete() error {
+	err := os.Remove(f.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (f *FileTokenStore) Lock() (func(), error) {
+	return lockFile(f.Path + ".lock")
+}
+
+type MemoryTokenStore struct {
+	mu   sync.RWMutex
+	info *TokenInfo
+}
+
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{}
+}
+
+func (m *MemoryTokenStore) Load() (*TokenInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.info == nil {
+		return nil, ErrTokenNotFound
+	}
+	info := *m.info
+	return &info, nil
+}
+
+func (m *MemoryTokenStore) Save(info *TokenInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *info
+	m.info = &saved
+	return nil
+}
+
+func (m *MemoryTokenStore) Delete() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.info = nil
+	return nil
+}
+
+type NoopTokenStore struct{}
+
+func (NoopTokenStore) Load() (*TokenInfo, error) {
+	return nil, ErrTokenNotFound
+}
+
+func (NoopTokenStore) Save(*TokenInfo) error {
+	return nil
+}
+
+func (NoopTokenStore) Delete() error {
+	return nil
+}
+
+func getTokenStore(options *ClientOptions) TokenStore {
+	if options.TokenStore != nil {
+		return options.TokenStore
+	}
+	return NewFileTokenStore(options.TokenFilePath)
+}
+
+func decodeTokenInfo(b []byte) (*TokenInfo, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, ErrTokenNotFound
+	}
+	if b[0] != '{' {
+		return &TokenInfo{Token: string(b)}, nil
+	}
+
+	var info TokenInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	if info.Token == "" {
+		return nil, ErrTokenNotFound
+	}
+	return &info, nil
+}
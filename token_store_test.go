@@ -0,0 +1,198 @@
+package vault
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenStores(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		name  string
+		store TokenStore
+	}{
+		{name: "file", store: NewFileTokenStore(filepath.Join(dir, ".token"))},
+		{name: "memory", store: NewMemoryTokenStore()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.store.Load()
+			assert.Equal(t, ErrTokenNotFound, err)
+
+			info := &TokenInfo{Token: "test_token", Accessor: "accessor", ExpireTime: time.Unix(1600000000, 0).UTC()}
+			assert.Nil(t, tc.store.Save(info))
+			actual, err := tc.store.Load()
+			assert.Nil(t, err)
+			assert.Equal(t, info, actual)
+
+			assert.Nil(t, tc.store.Delete())
+			_, err = tc.store.Load()
+			assert.Equal(t, ErrTokenNotFound, err)
+			assert.Nil(t, tc.store.Delete())
+		})
+	}
+}
+
+func TestNoopTokenStore(t *testing.T) {
+	store := NoopTokenStore{}
+
+	assert.Nil(t, store.Save(&TokenInfo{Token: "test_token"}))
+	_, err := store.Load()
+	assert.Equal(t, ErrTokenNotFound, err)
+	assert.Nil(t, store.Delete())
+}
+
+func TestGetTokenStore(t *testing.T) {
+	store := NewMemoryTokenStore()
+
+	assert.Equal(t, store, getTokenStore(&ClientOptions{TokenStore: store}))
+	assert.Equal(t, &FileTokenStore{Path: "/tmp/.token"}, getTokenStore(&ClientOptions{TokenFilePath: "/tmp/.token"}))
+}
+
+func TestClientMemoryTokenStore(t *testing.T) {
+	server := newTestVaultServer(t, func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	defer server.Close()
+
+	store := NewMemoryTokenStore()
+	client := newTestClient(t, server, &ClientOptions{TokenStore: store})
+
+	_, err := client.Get("secret/path")
+	assert.Nil(t, err)
+
+	info, _ := store.Load()
+	assert.Equal(t, "test_token", info.Token)
+	_, err = os.Stat(client.options.TokenFilePath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileTokenStoreSave(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	store := NewFileTokenStore(filepath.Join(dir, ".token"))
+	assert.Nil(t, store.Save(&TokenInfo{Token: "test_token", Address: "https://vault:8200/v1"}))
+
+	info, _ := os.Stat(store.Path)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	files, _ := ioutil.ReadDir(dir)
+	assert.Equal(t, 1, len(files))
+}
+
+func TestFileTokenStoreLock(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	store := NewFileTokenStore(filepath.Join(dir, ".token"))
+	unlock, err := store.Lock()
+	assert.Nil(t, err)
+
+	var locked int32
+	done := make(chan struct{})
+	go func() {
+		unlock, err := store.Lock()
+		assert.Nil(t, err)
+		atomic.StoreInt32(&locked, 1)
+		unlock()
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&locked))
+	unlock()
+	<-done
+	assert.Equal(t, int32(1), atomic.LoadInt32(&locked))
+}
+
+func TestClientTokenConcurrentLogin(t *testing.T) {
+	var logins int32
+	server := newTestVaultServer(t, nil)
+	defer server.Close()
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/auth/approle/login":
+			atomic.AddInt32(&logins, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"test_token","accessor":"accessor","lease_duration":3600}}`))
+		default:
+			_, _ = w.Write([]byte(`{"data":{"ttl":3600,"renewable":true}}`))
+		}
+	})
+
+	client := newTestClient(t, server, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			token, err := client.token(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, "test_token", *token)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&logins))
+	info, _ := client.tokens.Load()
+	assert.Equal(t, "accessor", info.Accessor)
+	assert.Equal(t, "approle", info.AuthMethod)
+	assert.Equal(t, client.api.baseUrl(), info.Address)
+}
+
+func TestClientTokenOtherAddress(t *testing.T) {
+	server := newTestVaultServer(t, nil)
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	_ = client.tokens.Save(&TokenInfo{Token: "other_token", Address: "https://other:8200/v1"})
+
+	token, err := client.token(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "test_token", *token)
+}
+
+func TestDecodeTokenInfo(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		expect *TokenInfo
+		err    bool
+	}{
+		{name: "bareToken", input: "bare_token\n", expect: &TokenInfo{Token: "bare_token"}},
+		{name: "json", input: `{"token":"json_token","role":"role"}`, expect: &TokenInfo{Token: "json_token", Role: "role"}},
+		{name: "empty", input: " \n", err: true},
+		{name: "jsonWithoutToken", input: `{"role":"role"}`, err: true},
+		{name: "brokenJson", input: `{"token":`, err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, err := decodeTokenInfo([]byte(tc.input))
+			if tc.err {
+				assert.Error(t, err, "")
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, info)
+		})
+	}
+}
+
+func TestTokenInfoTtl(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, time.Duration(-1), TokenInfo{}.Ttl(now))
+	assert.Equal(t, time.Minute, TokenInfo{ExpireTime: now.Add(time.Minute)}.Ttl(now))
+}
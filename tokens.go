@@ -0,0 +1,213 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+)
+
+const (
+	tokenCreateLink         = "auth/token/create"
+	tokenLookupLink         = "auth/token/lookup"
+	tokenLookupAccessorLink = "auth/token/lookup-accessor"
+	tokenRenewLink          = "auth/token/renew"
+	tokenRevokeAccessorLink = "auth/token/revoke-accessor"
+)
+
+type TokenCreateRequest struct {
+	Id              string            // собственный id токена (нужен root)
+	Policies        []string          // политики токена
+	Meta            map[string]string // метаданные токена
+	NoParent        bool              // orphan токен без родителя
+	NoDefaultPolicy bool              // не добавлять политику default
+	Renewable       *bool             // nil - по умолчанию Vault
+	Ttl             time.Duration     // начальный ttl
+	ExplicitMaxTtl  time.Duration     // жесткий максимальный ttl
+	Period          time.Duration     // периодический токен
+	Type            string            // service или batch
+	DisplayName     string            // имя токена
+	NumUses         int               // число использований, 0 - без ограничений
+}
+
+type TokenAuth struct {
+	ClientToken   string            `json:"client_token"`
+	Accessor      string            `json:"accessor"`
+	Policies      []string          `json:"policies"`
+	TokenPolicies []string          `json:"token_policies"`
+	Metadata      map[string]string `json:"metadata"`
+	LeaseDuration int               `json:"lease_duration"`
+	Renewable     bool              `json:"renewable"`
+	EntityId      string            `json:"entity_id"`
+	TokenType     string            `json:"token_type"`
+	Orphan        bool              `json:"orphan"`
+	NumUses       int               `json:"num_uses"`
+}
+
+type TokenLookup struct {
+	Id             string            `json:"id"`
+	Accessor       string            `json:"accessor"`
+	DisplayName    string            `json:"display_name"`
+	Policies       []string          `json:"policies"`
+	Meta           map[string]string `json:"meta"`
+	Path           string            `json:"path"`
+	EntityId       string            `json:"entity_id"`
+	Type           string            `json:"type"`
+	Orphan         bool              `json:"orphan"`
+	Renewable      bool              `json:"renewable"`
+	NumUses        int               `json:"num_uses"`
+	Ttl            int               `json:"ttl"`
+	CreationTtl    int               `json:"creation_ttl"`
+	ExplicitMaxTtl int               `json:"explicit_max_ttl"`
+	Period         int               `json:"period"`
+	CreationTime   int64             `json:"creation_time"`
+	IssueTime      time.Time         `json:"issue_time"`
+	ExpireTime     *time.Time        `json:"expire_time"`
+}
+
+func (c Client) CreateToken(ctx context.Context, request *TokenCreateRequest) (*TokenAuth, error) {
+	return c.createToken(ctx, tokenCreateLink, request)
+}
+
+func (c Client) CreateRoleToken(ctx context.Context, role string, request *TokenCreateRequest) (*TokenAuth, error) {
+	if role == "" {
+		return nil, errors.New("token role is empty")
+	}
+	return c.createToken(ctx, path.Join(tokenCreateLink, role), request)
+}
+
+func (c Client) LookupToken(ctx context.Context, token string) (*TokenLookup, error) {
+	return c.lookupToken(ctx, tokenLookupLink, map[string]string{"token": token})
+}
+
+func (c Client) LookupAccessor(ctx context.Context, accessor string) (*TokenLookup, error) {
+	return c.lookupToken(ctx, tokenLookupAccessorLink, map[string]string{"accessor": accessor})
+}
+
+func (c Client) RenewToken(ctx context.Context, token string, increment time.Duration) (*TokenAuth, error) {
+	data := map[string]string{"token": token}
+	if increment > 0 {
+		data["increment"] = durationString(increment)
+	}
+
+	response, err := c.write(ctx, tokenRenewLink, data)
+	if err != nil {
+		return nil, err
+	}
+	return decodeTokenAuth(response)
+}
+
+func (c Client) RevokeAccessor(ctx context.Context, accessor string) error {
+	_, err := c.write(ctx, tokenRevokeAccessorLink, map[string]string{"accessor": accessor})
+	return err
+}
+
+func (c Client) createToken(ctx context.Context, link string, request *TokenCreateRequest) (*TokenAuth, error) {
+	if request == nil {
+		request = &TokenCreateRequest{}
+	}
+
+	response, err := c.write(ctx, link, request.body())
+	if err != nil {
+		return nil, err
+	}
+	return decodeTokenAuth(response)
+}
+
+func (c Client) lookupToken(ctx context.Context, link string, data map[string]string) (*TokenLookup, error) {
+	response, err := c.write(ctx, link, data)
+	if err != nil {
+		return nil, err
+	}
+
+	type jsonResponse struct {
+		Data *TokenLookup `json:"data"`
+	}
+	var lookupResponse jsonResponse
+
+	err = json.Unmarshal(response, &lookupResponse)
+	if err != nil {
+		return nil, err
+	}
+	if lookupResponse.Data == nil {
+		return nil, errors.New("vault response has no token data")
+	}
+	return lookupResponse.Data, nil
+}
+
+func (c Client) write(ctx context.Context, link string, data interface{}) ([]byte, error) {
+	requestData, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.request(ctx, "POST", link, *token, requestData)
+}
+
+func (r TokenCreateRequest) body() map[string]interface{} {
+	body := map[string]interface{}{}
+	if r.Id != "" {
+		body["id"] = r.Id
+	}
+	if len(r.Policies) > 0 {
+		body["policies"] = r.Policies
+	}
+	if len(r.Meta) > 0 {
+		body["meta"] = r.Meta
+	}
+	if r.NoParent {
+		body["no_parent"] = true
+	}
+	if r.NoDefaultPolicy {
+		body["no_default_policy"] = true
+	}
+	if r.Renewable != nil {
+		body["renewable"] = *r.Renewable
+	}
+	if r.Ttl > 0 {
+		body["ttl"] = durationString(r.Ttl)
+	}
+	if r.ExplicitMaxTtl > 0 {
+		body["explicit_max_ttl"] = durationString(r.ExplicitMaxTtl)
+	}
+	if r.Period > 0 {
+		body["period"] = durationString(r.Period)
+	}
+	if r.Type != "" {
+		body["type"] = r.Type
+	}
+	if r.DisplayName != "" {
+		body["display_name"] = r.DisplayName
+	}
+	if r.NumUses > 0 {
+		body["num_uses"] = r.NumUses
+	}
+	return body
+}
+
+func decodeTokenAuth(response []byte) (*TokenAuth, error) {
+	type jsonResponse struct {
+		Auth *TokenAuth `json:"auth"`
+	}
+	var authResponse jsonResponse
+
+	err := json.Unmarshal(response, &authResponse)
+	if err != nil {
+		return nil, err
+	}
+	if authResponse.Auth == nil {
+		return nil, errors.New("vault response has no auth data")
+	}
+	return authResponse.Auth, nil
+}
+
+func durationString(d time.Duration) string {
+	return fmt.Sprintf("%ds", int64(d.Round(time.Second)/time.Second))
+}
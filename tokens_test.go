@@ -0,0 +1,167 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testTokenRequest struct {
+	path  string
+	token string
+	body  map[string]interface{}
+}
+
+func newTestTokenServer(t *testing.T, requests *[]testTokenRequest, response string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/v1/auth/token/lookup-self" {
+			_, _ = w.Write([]byte(`{"data":{"ttl":3600}}`))
+			return
+		}
+
+		assert.Equal(t, "POST", req.Method)
+		b, _ := ioutil.ReadAll(req.Body)
+		body := map[string]interface{}{}
+		_ = json.Unmarshal(b, &body)
+		*requests = append(*requests, testTokenRequest{path: req.URL.Path, token: req.Header.Get("X-Vault-Token"), body: body})
+
+		_, _ = w.Write([]byte(response))
+	}))
+}
+
+func TestCreateToken(t *testing.T) {
+	renewable := false
+	testCases := []struct {
+		name    string
+		role    string
+		request *TokenCreateRequest
+		path    string
+		body    map[string]interface{}
+	}{
+		{
+			name:    "default",
+			request: nil,
+			path:    "/v1/auth/token/create",
+			body:    map[string]interface{}{},
+		},
+		{
+			name: "orphanBatch",
+			request: &TokenCreateRequest{
+				Policies:       []string{"read"},
+				NoParent:       true,
+				Type:           "batch",
+				Ttl:            5 * time.Minute,
+				ExplicitMaxTtl: time.Hour,
+				NumUses:        3,
+				Renewable:      &renewable,
+			},
+			path: "/v1/auth/token/create",
+			body: map[string]interface{}{
+				"policies":         []interface{}{"read"},
+				"no_parent":        true,
+				"type":             "batch",
+				"ttl":              "300s",
+				"explicit_max_ttl": "3600s",
+				"num_uses":         float64(3),
+				"renewable":        false,
+			},
+		},
+		{
+			name:    "periodicRole",
+			role:    "scheduler",
+			request: &TokenCreateRequest{Period: 24 * time.Hour, Meta: map[string]string{"job": "id"}},
+			path:    "/v1/auth/token/create/scheduler",
+			body: map[string]interface{}{
+				"period": "86400s",
+				"meta":   map[string]interface{}{"job": "id"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var requests []testTokenRequest
+			server := newTestTokenServer(t, &requests, `{"auth":{"client_token":"child_token","accessor":"child_accessor","lease_duration":300,"orphan":true}}`)
+			defer server.Close()
+
+			client := newTestClient(t, server, nil)
+			_ = client.tokens.Save(&TokenInfo{Token: "parent_token"})
+
+			var auth *TokenAuth
+			var err error
+			if tc.role != "" {
+				auth, err = client.CreateRoleToken(context.Background(), tc.role, tc.request)
+			} else {
+				auth, err = client.CreateToken(context.Background(), tc.request)
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, "child_token", auth.ClientToken)
+			assert.Equal(t, "child_accessor", auth.Accessor)
+			assert.Equal(t, 300, auth.LeaseDuration)
+			assert.True(t, auth.Orphan)
+			assert.Equal(t, []testTokenRequest{{path: tc.path, token: "parent_token", body: tc.body}}, requests)
+		})
+	}
+}
+
+func TestCreateRoleTokenNegative1(t *testing.T) {
+	client := Client{}
+	_, err := client.CreateRoleToken(context.Background(), "", nil)
+	assert.Error(t, err, "")
+}
+
+func TestLookupToken(t *testing.T) {
+	var requests []testTokenRequest
+	server := newTestTokenServer(t, &requests, `{"data":{"id":"child_token","accessor":"child_accessor","ttl":60,"policies":["read"],"type":"service"}}`)
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	_ = client.tokens.Save(&TokenInfo{Token: "parent_token"})
+
+	lookup, err := client.LookupToken(context.Background(), "child_token")
+	assert.Nil(t, err)
+	assert.Equal(t, &TokenLookup{Id: "child_token", Accessor: "child_accessor", Ttl: 60, Policies: []string{"read"}, Type: "service"}, lookup)
+
+	_, err = client.LookupAccessor(context.Background(), "child_accessor")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []testTokenRequest{
+		{path: "/v1/auth/token/lookup", token: "parent_token", body: map[string]interface{}{"token": "child_token"}},
+		{path: "/v1/auth/token/lookup-accessor", token: "parent_token", body: map[string]interface{}{"accessor": "child_accessor"}},
+	}, requests)
+}
+
+func TestRenewAndRevokeToken(t *testing.T) {
+	var requests []testTokenRequest
+	server := newTestTokenServer(t, &requests, `{"auth":{"client_token":"child_token","lease_duration":600,"renewable":true}}`)
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	_ = client.tokens.Save(&TokenInfo{Token: "parent_token"})
+
+	auth, err := client.RenewToken(context.Background(), "child_token", 10*time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 600, auth.LeaseDuration)
+	assert.True(t, auth.Renewable)
+
+	assert.Nil(t, client.RevokeAccessor(context.Background(), "child_accessor"))
+
+	assert.Equal(t, []testTokenRequest{
+		{path: "/v1/auth/token/renew", token: "parent_token", body: map[string]interface{}{"token": "child_token", "increment": "600s"}},
+		{path: "/v1/auth/token/revoke-accessor", token: "parent_token", body: map[string]interface{}{"accessor": "child_accessor"}},
+	}, requests)
+}
+
+func TestDecodeTokenAuthNegative1(t *testing.T) {
+	_, err := decodeTokenAuth([]byte(`{"data":{}}`))
+	assert.Error(t, err, "")
+
+	_, err = decodeTokenAuth([]byte(`{`))
+	assert.Error(t, err, "")
+}
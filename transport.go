@@ -0,0 +1,125 @@
+package vault
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	baseDialTimeout           = 10 * time.Second
+	baseKeepAlive             = 30 * time.Second
+	baseTLSHandshakeTimeout   = 10 * time.Second
+	baseResponseHeaderTimeout = 30 * time.Second
+	baseIdleConnTimeout       = 90 * time.Second
+	baseMaxIdleConns          = 100
+	baseMaxIdleConnsPerHost   = 10
+
+	unixScheme     = "unix"
+	unixHostSuffix = ".unix"
+)
+
+// Middleware wraps the transport used for Vault requests,
+// e.g. to add logging, metrics, headers or request signing.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to http.RoundTripper.
+type RoundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func newTransport(options *ClientOptions) (*http.Transport, error) {
+	dialer := &net.Dialer{
+		Timeout:   baseDialTimeout,
+		KeepAlive: baseKeepAlive,
+	}
+	if options.LocalAddr != "" {
+		ip := net.ParseIP(options.LocalAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid local address: %s", options.LocalAddr)
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	proxyFunc := http.ProxyFromEnvironment
+	if options.Proxy != "" {
+		proxyUrl, err := url.Parse(options.Proxy)
+		if err != nil || proxyUrl.Scheme == "" || proxyUrl.Host == "" {
+			return nil, fmt.Errorf("invalid proxy url: %s", options.Proxy)
+		}
+		proxyFunc = http.ProxyURL(proxyUrl)
+	}
+
+	dialContext := dialer.DialContext
+	if options.DialContext != nil {
+		dialContext = options.DialContext
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy(proxyFunc),
+		DialContext:           dial(dialer, dialContext),
+		TLSHandshakeTimeout:   baseTLSHandshakeTimeout,
+		ResponseHeaderTimeout: baseResponseHeaderTimeout,
+		IdleConnTimeout:       baseIdleConnTimeout,
+		MaxIdleConns:          baseMaxIdleConns,
+		MaxIdleConnsPerHost:   baseMaxIdleConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}, nil
+}
+
+// chain applies middleware so that the first one sees the request first.
+func chain(transport http.RoundTripper, middleware []Middleware) http.RoundTripper {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		transport = middleware[i](transport)
+	}
+	return transport
+}
+
+// Unix socket addresses are sent as http://<hex socket path>.unix, the host
+// keeps idle connections of different sockets apart and tells dial where to go.
+func unixSocketHost(socket string) string {
+	return hex.EncodeToString([]byte(socket)) + unixHostSuffix
+}
+
+func unixSocketPath(host string) (string, bool) {
+	if !strings.HasSuffix(host, unixHostSuffix) {
+		return "", false
+	}
+
+	socket, err := hex.DecodeString(strings.TrimSuffix(host, unixHostSuffix))
+	if err != nil {
+		return "", false
+	}
+	return string(socket), true
+}
+
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// dial sends unix socket hosts to the socket and everything else to next.
+func dial(dialer *net.Dialer, next dialFunc) dialFunc {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err == nil {
+			if socket, ok := unixSocketPath(host); ok {
+				return dialer.DialContext(ctx, "unix", socket)
+			}
+		}
+		return next(ctx, network, address)
+	}
+}
+
+func proxy(next func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(request *http.Request) (*url.URL, error) {
+		if _, ok := unixSocketPath(request.URL.Hostname()); ok {
+			return nil, nil
+		}
+		return next(request)
+	}
+}
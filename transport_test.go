@@ -0,0 +1,265 @@
+package vault
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func headerMiddleware(key, value string, calls *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			*calls = append(*calls, value)
+			request.Header.Add(key, value)
+			return next.RoundTrip(request)
+		})
+	}
+}
+
+func TestNewTransport(t *testing.T) {
+	transport, err := newTransport(&ClientOptions{})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, transport.DialContext)
+	assert.Equal(t, baseTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+	assert.Equal(t, baseResponseHeaderTimeout, transport.ResponseHeaderTimeout)
+	assert.Equal(t, baseIdleConnTimeout, transport.IdleConnTimeout)
+	assert.Equal(t, baseMaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, baseMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+}
+
+func TestNewTransportNegative(t *testing.T) {
+	testCases := []struct {
+		name    string
+		options *ClientOptions
+	}{
+		{name: "proxyWithoutScheme", options: &ClientOptions{Proxy: "proxy:3128"}},
+		{name: "proxyInvalid", options: &ClientOptions{Proxy: "://"}},
+		{name: "localAddrInvalid", options: &ClientOptions{LocalAddr: "localhost"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newTransport(tc.options)
+			assert.Error(t, err, "")
+		})
+	}
+}
+
+func TestActionsMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, []string{"first", "second"}, req.Header["X-Test"])
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		httpClient *http.Client
+	}{
+		{name: "defaultClient"},
+		{name: "customClient", httpClient: server.Client()},
+		{name: "customClientDefaultTransport", httpClient: &http.Client{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			actions, err := newActions(&ClientOptions{
+				TLS:        TLSOptions{UseSystemRoots: true},
+				HTTPClient: tc.httpClient,
+				Middleware: []Middleware{
+					headerMiddleware("X-Test", "first", &calls),
+					headerMiddleware("X-Test", "second", &calls),
+				},
+			})
+			assert.Nil(t, err)
+
+			_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"first", "second"}, calls)
+		})
+	}
+}
+
+func TestActionsHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {}))
+	defer server.Close()
+
+	httpClient := server.Client()
+	transport := httpClient.Transport
+
+	actions, err := newActions(&ClientOptions{
+		CertFilePath: "path_not_exist/file.txt",
+		HTTPClient:   httpClient,
+		Middleware:   []Middleware{func(next http.RoundTripper) http.RoundTripper { return next }},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, transport, httpClient.Transport)
+
+	_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+	assert.Nil(t, err)
+}
+
+func newTestAgentServer(t *testing.T, socket string, handler http.HandlerFunc) *httptest.Server {
+	listener, err := net.Listen("unix", socket)
+	assert.Nil(t, err)
+
+	server := httptest.NewUnstartedServer(handler)
+	_ = server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	return server
+}
+
+func TestClientAgentUnixSocket(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	var tokens []string
+	socket := filepath.Join(dir, "agent.sock")
+	server := newTestAgentServer(t, socket, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/secret/path", req.URL.Path)
+		assert.Equal(t, "true", req.Header.Get("X-Vault-Request"))
+		tokens = append(tokens, req.Header.Get("X-Vault-Token"))
+		if req.Header.Get("X-Vault-Token") == "revoked_token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"key":"value"}}`))
+	})
+	defer server.Close()
+
+	tokenFile := filepath.Join(dir, "sink")
+	client, err := NewCustomClient("", "", &ClientOptions{
+		TokenFilePath: tokenFile,
+	}, &ClientApi{Addresses: []string{"unix://" + socket}, Agent: true})
+	assert.Nil(t, err)
+	defer client.Close()
+
+	_, err = client.Get("secret/path")
+	assert.Nil(t, err)
+
+	_ = ioutil.WriteFile(tokenFile, []byte("agent_token"), 0600)
+	_, err = client.Get("secret/path")
+	assert.Nil(t, err)
+
+	_ = ioutil.WriteFile(tokenFile, []byte("revoked_token"), 0600)
+	_, err = client.Get("secret/path")
+	assert.Error(t, err, "")
+	assert.Nil(t, client.Logout(context.Background()))
+
+	raw, _ := ioutil.ReadFile(tokenFile)
+	assert.Equal(t, "revoked_token", string(raw))
+	assert.Equal(t, []string{"", "agent_token", "revoked_token"}, tokens)
+}
+
+// newTestProxy answers plain http requests itself and tunnels CONNECT to the target.
+func newTestProxy(t *testing.T, requests *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		*requests = append(*requests, req.Method+" "+req.Host)
+		if req.Method != http.MethodConnect {
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+
+		target, err := net.Dial("tcp", req.Host)
+		if !assert.Nil(t, err) {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		conn, _, err := w.(http.Hijacker).Hijack()
+		assert.Nil(t, err)
+		_, _ = conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		go func() {
+			_, _ = io.Copy(target, conn)
+			_ = target.Close()
+		}()
+		_, _ = io.Copy(conn, target)
+		_ = conn.Close()
+	}))
+}
+
+func TestActionsProxy(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "ca")
+	caFile := writeTestFile(t, dir, "ca.pem", ca.certPEM)
+	tlsServer := newTestTLSServer(t, ca, nil)
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		t.Error("request bypassed proxy")
+	}))
+	defer plainServer.Close()
+
+	var requests []string
+	proxyServer := newTestProxy(t, &requests)
+	defer proxyServer.Close()
+
+	testCases := []struct {
+		name   string
+		url    string
+		expect string
+	}{
+		{name: "connect", url: tlsServer.URL, expect: "CONNECT " + tlsServer.Listener.Addr().String()},
+		{name: "plain", url: plainServer.URL, expect: "GET " + plainServer.Listener.Addr().String()},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests = nil
+			actions, err := newActions(&ClientOptions{CertFilePath: caFile, Proxy: proxyServer.URL})
+			assert.Nil(t, err)
+
+			_, err = actions.request(context.Background(), "GET", tc.url, "", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{tc.expect}, requests)
+		})
+	}
+}
+
+func TestActionsDialContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "127.0.0.1", req.RemoteAddr[:len("127.0.0.1")])
+	}))
+	defer server.Close()
+
+	var dialed []string
+	dialer := &net.Dialer{}
+	testCases := []struct {
+		name    string
+		options *ClientOptions
+		dialed  []string
+	}{
+		{name: "localAddr", options: &ClientOptions{LocalAddr: "127.0.0.1"}},
+		{
+			name: "dialContext",
+			options: &ClientOptions{DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				dialed = append(dialed, address)
+				return dialer.DialContext(ctx, network, address)
+			}},
+			dialed: []string{server.Listener.Addr().String()},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dialed = nil
+			tc.options.TLS.UseSystemRoots = true
+			actions, err := newActions(tc.options)
+			assert.Nil(t, err)
+
+			_, err = actions.request(context.Background(), "GET", server.URL, "", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, tc.dialed, dialed)
+		})
+	}
+}
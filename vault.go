@@ -1,11 +1,14 @@
 package vault
 
 import (
+	"context"
 	"encoding/json"
-	"io/ioutil"
+	"errors"
+	"net/http"
 	"net/url"
-	"os"
 	"path"
+	"strings"
+	"time"
 )
 
 type Client struct {
@@ -14,6 +17,10 @@ type Client struct {
 	options *ClientOptions
 	actions *httpActions
 	api     *ClientApi
+	tokens  TokenStore
+	cache   *secretCache
+
+	endpoints *endpointPool
 }
 
 type credentials struct {
@@ -22,169 +29,373 @@ type credentials struct {
 }
 
 func NewBasicClient(roleId, secretId string, options *ClientOptions) (*Client, error) {
-	var cliOpt *ClientOptions
-	if options == nil {
-		cliOpt = getBaseClientOptions()
-	} else {
-		cliOpt = &ClientOptions{
-			TokenFilePath: getTokenFilePath(options.TokenFilePath),
-			CertFilePath:  getCertFilePath(options.CertFilePath),
+	return New(withClientOptions(options), withCredentials(roleId, secretId))
+}
+
+func NewCustomClient(roleId, secretId string, options *ClientOptions, api *ClientApi) (*Client, error) {
+	return New(withClientOptions(options), withClientApi(api), withCredentials(roleId, secretId))
+}
+
+func (c Client) Get(dataUrl string) (interface{}, error) {
+	u, _ := url.Parse(c.api.baseUrl())
+	u.Path = path.Join(u.Path, dataUrl)
+
+	if c.cache != nil {
+		if data, ok := c.cache.get(u.String()); ok {
+			return data, nil
 		}
 	}
 
-	actions, err := newActions(cliOpt.CertFilePath)
+	data, leaseDuration, err := c.get(context.Background(), dataUrl)
 	if err != nil {
+		if c.cache != nil && isUnavailable(err) {
+			if data, ok := c.cache.getStale(u.String()); ok {
+				return data, nil
+			}
+		}
 		return nil, err
 	}
 
-	return &Client{
-		credentials: credentials{RoleId: roleId, SecretId: secretId},
-		options:     cliOpt,
-		actions:     actions,
-		api:         getBaseClientApi(),
-	}, nil
+	if c.cache != nil {
+		c.cache.set(u.String(), data, leaseDuration)
+	}
+	return data, nil
 }
 
-func NewCustomClient(roleId, secretId string, options *ClientOptions, api *ClientApi) (*Client, error) {
-	var cliOpt *ClientOptions
-	if options == nil {
-		cliOpt = getBaseClientOptions()
-	} else {
-		cliOpt = &ClientOptions{
-			TokenFilePath: getTokenFilePath(options.TokenFilePath),
-			CertFilePath:  getCertFilePath(options.CertFilePath),
+func (c Client) Logout(ctx context.Context) error {
+	if c.api.Agent {
+		return nil
+	}
+	// a static token is not kept in TokenStore, the stored one may belong to another client
+	if c.options.Token != "" {
+		_, err := c.request(ctx, "POST", revokeLink, c.options.Token, nil)
+		if err != nil && !isForbidden(err) {
+			return err
 		}
+		return nil
 	}
 
-	var cliApi *ClientApi
-	if api == nil {
-		cliApi = getBaseClientApi()
-	} else {
-		cliApi = &ClientApi{
-			Host:       getHost(api.Host),
-			Port:       getPort(api.Port),
-			Version:    getVersion(api.Version),
-			AuthLink:   getAuthLink(api.AuthLink),
-			UpdateLink: getUpdateLink(api.UpdateLink),
-			LookupLink: getLookupLink(api.LookupLink),
+	if locker, ok := c.tokens.(TokenLocker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return err
 		}
+		defer unlock()
 	}
 
-	actions, err := newActions(cliOpt.CertFilePath)
-	if err != nil {
-		return nil, err
+	info, err := c.tokens.Load()
+	if err == ErrTokenNotFound {
+		return nil
+	}
+	if err == nil {
+		_, err = c.request(ctx, "POST", revokeLink, info.Token, nil)
+		if err != nil && !isForbidden(err) {
+			return err
+		}
 	}
 
-	return &Client{
-		credentials: credentials{RoleId: roleId, SecretId: secretId},
-		options:     cliOpt,
-		actions:     actions,
-		api:         cliApi,
-	}, nil
+	return c.tokens.Delete()
 }
 
-func (c Client) Get(dataUrl string) (interface{}, error) {
-	u, _ := url.Parse(c.api.baseUrl())
-	u.Path = path.Join(u.Path, dataUrl)
+func (c Client) Close() error {
+	c.endpoints.close()
+	c.actions.close()
+
+	if c.options.RevokeOnClose {
+		return c.Logout(context.Background())
+	}
+	return nil
+}
 
-	token, err := c.token()
+func (c Client) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return c.cache.stats()
+}
+
+func (c Client) LimitStats() LimitStats {
+	if c.actions.limiter == nil {
+		return LimitStats{}
+	}
+	return c.actions.limiter.stats()
+}
+
+// CircuitState returns the circuit breaker state of a Vault address.
+func (c Client) CircuitState(address string) CircuitState {
+	if c.actions.breaker == nil {
+		return CircuitClosed
+	}
+	return c.actions.breaker.state(addressUrl(address).Host)
+}
+
+func (c Client) get(ctx context.Context, link string) (interface{}, time.Duration, error) {
+	token, err := c.token(ctx)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
-	response, err := c.actions.get(u.String(), *token)
+	response, err := c.request(ctx, "GET", link, *token, nil)
+	if isForbidden(err) && c.tokenRevoked(ctx, *token) {
+		c.invalidateToken(*token)
+		if token, err = c.token(ctx); err != nil {
+			return nil, 0, err
+		}
+		response, err = c.request(ctx, "GET", link, *token, nil)
+	}
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	type vaultData struct {
 		Data interface{} `json:"data"`
 	}
-	var data vaultData
+	type vaultResponse struct {
+		vaultData
+		LeaseDuration int `json:"lease_duration"`
+	}
+	var data vaultResponse
 
 	err = json.Unmarshal(response, &data)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	return data, nil
+	return data.vaultData, time.Duration(data.LeaseDuration) * time.Second, nil
 }
 
-func (c Client) token() (*string, error) {
+func (c Client) token(ctx context.Context) (*string, error) {
+	if c.options.Token != "" {
+		token := c.options.Token
+		return &token, nil
+	}
+	if c.api.Agent {
+		return c.agentToken()
+	}
+
+	if locker, ok := c.tokens.(TokenLocker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
 	requestData, _ := json.Marshal(c.credentials)
 
-	if _, err := os.Stat(c.options.TokenFilePath); os.IsNotExist(err) {
-		return c.__auth__(requestData)
+	info, err := c.tokens.Load()
+	if err != nil || !c.ownsToken(info) {
+		return c.__auth__(ctx, requestData)
 	}
 
-	bufToken, _ := ioutil.ReadFile(c.options.TokenFilePath)
-	ttl, renewable, err := c.__lookup__(string(bufToken))
-	if err != nil {
-		return c.__auth__(requestData)
+	if info.ExpireTime.IsZero() {
+		info, err = c.__lookup__(ctx, info.Token)
+		if err != nil {
+			return c.__auth__(ctx, requestData)
+		}
+		if !info.ExpireTime.IsZero() {
+			_, _ = c.storeToken(info)
+		}
 	}
 
-	if renewable && ttl < 1500 {
-		return c.__update__(requestData, string(bufToken))
+	switch c.options.Renewal.action(info, time.Now()) {
+	case renewToken:
+		return c.__update__(ctx, requestData, info)
+	case renewReauth:
+		return c.__auth__(ctx, requestData)
 	}
 
-	token := string(bufToken)
+	return &info.Token, nil
+}
+
+// agentToken returns the token written by Vault Agent, if any. Without it
+// requests go with X-Vault-Request only and the agent adds its auto-auth token.
+func (c Client) agentToken() (*string, error) {
+	var token string
+	if info, err := c.tokens.Load(); err == nil {
+		token = info.Token
+	}
 	return &token, nil
 }
 
-func (c Client) __auth__(requestData []byte) (*string, error) {
-	response, err := c.actions.post(c.api.authUrl(), "", requestData)
+// tokenRevoked checks the token after 403. Vault answers 403 for a path missing
+// from the token policies as well, so only a failed lookup-self means re-auth.
+func (c Client) tokenRevoked(ctx context.Context, token string) bool {
+	if c.options.Token != "" || c.api.Agent {
+		return false
+	}
+	_, err := c.__lookup__(ctx, token)
+	return isForbidden(err)
+}
+
+func (c Client) invalidateToken(token string) {
+	if c.api.Agent {
+		return
+	}
+
+	if locker, ok := c.tokens.(TokenLocker); ok {
+		unlock, err := locker.Lock()
+		if err != nil {
+			return
+		}
+		defer unlock()
+	}
+
+	info, err := c.tokens.Load()
+	if err == nil && info.Token == token {
+		_ = c.tokens.Delete()
+	}
+}
+
+func (c Client) ownsToken(info *TokenInfo) bool {
+	if info.Address != "" && info.Address != c.api.baseUrl() {
+		return false
+	}
+	if !info.ExpireTime.IsZero() && !time.Now().Before(info.ExpireTime) {
+		return false
+	}
+	return true
+}
+
+func (c Client) __auth__(ctx context.Context, requestData []byte) (*string, error) {
+	response, err := c.request(ctx, "POST", c.api.AuthLink, "", requestData)
 	if err != nil {
 		return nil, err
 	}
 
-	return createTokenFile(response, c.options.TokenFilePath)
+	return c.saveToken(response)
 }
 
-func (c Client) __update__(requestData []byte, token string) (*string, error) {
-	response, err := c.actions.post(c.api.updateUrl(), token, requestData)
+func (c Client) __update__(ctx context.Context, requestData []byte, info *TokenInfo) (*string, error) {
+	now := time.Now()
+	response, err := c.request(ctx, "POST", c.api.UpdateLink, info.Token, c.options.Renewal.body())
+	if err != nil {
+		return c.__auth__(ctx, requestData)
+	}
 
+	renewed, err := newTokenInfo(response, now)
 	if err != nil {
 		return nil, err
 	}
-	return createTokenFile(response, c.options.TokenFilePath)
+	if !info.IssueTime.IsZero() {
+		renewed.IssueTime = info.IssueTime
+	}
+
+	renewal := c.options.Renewal
+	if !renewed.ExpireTime.After(info.ExpireTime) || renewal.capped(info, renewed) || renewal.action(renewed, now) != renewNone {
+		return c.__auth__(ctx, requestData)
+	}
+	return c.storeToken(renewed)
 }
 
-func (c Client) __lookup__(token string) (int, bool, error) {
+func (c Client) saveToken(response []byte) (*string, error) {
+	info, err := newTokenInfo(response, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return c.storeToken(info)
+}
+
+func (c Client) storeToken(info *TokenInfo) (*string, error) {
+	info.AuthMethod = authMethod(c.api.AuthLink)
+	info.Address = c.api.baseUrl()
+	err := c.tokens.Save(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &info.Token, nil
+}
+
+func (c Client) __lookup__(ctx context.Context, token string) (*TokenInfo, error) {
+	type jsonResponseMeta struct {
+		RoleName string `json:"role_name"`
+	}
 	type jsonResponseData struct {
-		Ttl       int  `json:"ttl"`
-		Renewable bool `json:"renewable"`
+		Accessor  string           `json:"accessor"`
+		Ttl       int              `json:"ttl"`
+		Renewable bool             `json:"renewable"`
+		Policies  []string         `json:"policies"`
+		IssueTime time.Time        `json:"issue_time"`
+		Meta      jsonResponseMeta `json:"meta"`
 	}
 	type jsonResponse struct {Data jsonResponseData `json:"data"`}
 
-	response, err := c.actions.get(c.api.lookupUrl(), token)
+	now := time.Now()
+	response, err := c.request(ctx, "GET", c.api.LookupLink, token, nil)
 	if err != nil {
-		return 0, false, err
+		return nil, err
 	}
 
 	var lookupResponse jsonResponse
 	err = json.Unmarshal(response, &lookupResponse)
 	if err != nil {
-		return 0, false, err
+		return nil, err
 	}
 
-	return lookupResponse.Data.Ttl, lookupResponse.Data.Renewable, nil
+	data := lookupResponse.Data
+	info := &TokenInfo{
+		Token:     token,
+		Accessor:  data.Accessor,
+		IssueTime: data.IssueTime,
+		Renewable: data.Renewable,
+		Policies:  data.Policies,
+		Role:      data.Meta.RoleName,
+	}
+	if data.Ttl > 0 {
+		info.ExpireTime = now.Add(time.Duration(data.Ttl) * time.Second)
+	}
+	return info, nil
 }
 
-func createTokenFile(response []byte, tokenPath string) (*string, error) {
-	type authJson struct {Token string   `json:"client_token"`}
-	type respJson struct {Auth  authJson `json:"auth"`}
+func newTokenInfo(response []byte, issueTime time.Time) (*TokenInfo, error) {
+	type authMetaJson struct {
+		RoleName string `json:"role_name"`
+	}
+	type authJson struct {
+		Token         string       `json:"client_token"`
+		Accessor      string       `json:"accessor"`
+		LeaseDuration int          `json:"lease_duration"`
+		Renewable     bool         `json:"renewable"`
+		Policies      []string     `json:"policies"`
+		Metadata      authMetaJson `json:"metadata"`
+	}
+	type respJson struct {Auth authJson `json:"auth"`}
 
 	var respJsonData respJson
 	err := json.Unmarshal(response, &respJsonData)
 	if err != nil {
 		return nil, err
 	}
+	if respJsonData.Auth.Token == "" {
+		return nil, errors.New("vault response has no client token")
+	}
 
-	b := []byte(respJsonData.Auth.Token)
-	err = ioutil.WriteFile(tokenPath, b, 0644)
-	if err != nil {
-		return nil, err
+	auth := respJsonData.Auth
+	info := &TokenInfo{
+		Token:         auth.Token,
+		Accessor:      auth.Accessor,
+		IssueTime:     issueTime,
+		LeaseDuration: auth.LeaseDuration,
+		Renewable:     auth.Renewable,
+		Policies:      auth.Policies,
+		Role:          auth.Metadata.RoleName,
+	}
+	if auth.LeaseDuration > 0 {
+		info.ExpireTime = issueTime.Add(time.Duration(auth.LeaseDuration) * time.Second)
 	}
 
-	return &respJsonData.Auth.Token, nil
+	return info, nil
+}
+
+func authMethod(authLink string) string {
+	parts := strings.Split(strings.Trim(authLink, "/"), "/")
+	if len(parts) >= 2 && parts[0] == "auth" {
+		return parts[1]
+	}
+	return ""
 }
 
+func isForbidden(err error) bool {
+	responseErr, ok := err.(*ResponseError)
+	return ok && responseErr.StatusCode == http.StatusForbidden
+}
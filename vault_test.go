@@ -1,25 +1,43 @@
 package vault
 
 import (
+	"context"
 	"crypto/tls"
+	"encoding/json"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const certTestVault = `
 -----BEGIN CERTIFICATE-----
-MIIFMzCCAxugAwIBAgIUFba3dcyDp9qymQCgryrpvUfUaFswDQYJKoZIhvcNAQEL
-BQAwITEfMB0GA1UEAxMWU0VBUkNIIE1BSUwuUlUgUm9vdCBDQTAeFw0xOTA3MDMx
-MzM5MjVaFw0yOTA2MzAxMzM5NTNaMCExHzAdBgNVBAMTFlNFQVJDSCBNQUlMLlJV
+MIIBezCCASCgAwIBAgIIGN/p8ccL39wwCgYIKoZIzj0EAwIwIDEeMBwGA1UEAxMV
+R08tVkFVTFQgVEVTVCBSb290IENBMCAXDTIwMDEwMTAwMDAwMFoYDzIxMjAwMTAx
+MDAwMDAwWjAgMR4wHAYDVQQDExVHTy1WQVVMVCBURVNUIFJvb3QgQ0EwWTATBgcq
+hkjOPQIBBggqhkjOPQMBBwNCAAQN0oZ1PcKUtIHGWL7rruNdrLCtl6K5FIABXmQD
+ZuLvKh19qUIZ+/e0p1DZAUAsGkDv5zz9NsC12SimlQ9Hwhp8o0IwQDAOBgNVHQ8B
+Af8EBAMCAgQwDwYDVR0TAQH/BAUwAwEB/zAdBgNVHQ4EFgQU3V/Vl8lWfI4Ff8PO
+CgVs5Gpp2dQwCgYIKoZIzj0EAwIDSQAwRgIhAJ/xVjBSG6ClWWiIo5e8aDkw5WcA
+L/otr5Q7OTTJS4L4AiEAvC+BHGg4iXhfwXKIaJqzZqf8/OdKxXsAOv1OjaFOSpY=
 -----END CERTIFICATE-----
 -----BEGIN CERTIFICATE-----
-MIIFhjCCA26gAwIBAgIUYswAasjzKm+hGa6uYIjAeAPnOBowDQYJKoZIhvcNAQEL
-BQAwITEfMB0GA1UEAxMWU0VBUkNIIE1BSUwuUlUgUm9vdCBDQTAeFw0xOTA3MTkx
-NjUxMjFaFw0yNDA3MTcxNjUxNTFaMC4xLDAqBgNVBAMTI1NFQVJDSCBNQUlMLlJV
+MIIBizCCATCgAwIBAgIIGN/p8ccR2kEwCgYIKoZIzj0EAwIwKDEmMCQGA1UEAxMd
+R08tVkFVTFQgVEVTVCBJbnRlcm1lZGlhdGUgQ0EwIBcNMjAwMTAxMDAwMDAwWhgP
+MjEyMDAxMDEwMDAwMDBaMCgxJjAkBgNVBAMTHUdPLVZBVUxUIFRFU1QgSW50ZXJt
+ZWRpYXRlIENBMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEqL2EEFXkrEhtb3FZ
+CLO+N0vZv05eAxV2gkCItV+W4AH7GJXuSDDRfpjSsxMZAKXUsVcIIcpfJ1z1S7XB
+O5KgGKNCMEAwDgYDVR0PAQH/BAQDAgIEMA8GA1UdEwEB/wQFMAMBAf8wHQYDVR0O
+BBYEFAsXTLHEQWS4K11IKke4Vgy3/11BMAoGCCqGSM49BAMCA0kAMEYCIQCdmaS0
+bNsXKmsOdbrfAbFR+t8RPHkkBobYIRr+sp0ruAIhAPfjOnGYWEV+2vu6wcMUV787
+xkt/Y5JMM6Kxr2UdY0TX
 -----END CERTIFICATE-----
 `
 
@@ -66,7 +84,7 @@ func TestNewBasicClient_Positive1(t *testing.T) {
 
 	actual, _ := NewBasicClient("roleId", "secretId", cliOpt)
 	assert.Equal(t, expect.credentials, actual.credentials)
-	assert.Equal(t, expect.actions, actual.actions)
+	assertActionsEqual(t, expect.actions, actual.actions)
 	assert.Equal(t, expect.options, actual.options)
 	assert.Equal(t, expect.api, actual.api)
 }
@@ -114,7 +132,7 @@ func TestNewCustomClient_Positive1(t *testing.T) {
 
 	actual, _ := NewCustomClient("roleId", "secretId", cliOpt, nil)
 	assert.Equal(t, expect.credentials, actual.credentials)
-	assert.Equal(t, expect.actions, actual.actions)
+	assertActionsEqual(t, expect.actions, actual.actions)
 	assert.Equal(t, expect.options, actual.options)
 	assert.Equal(t, expect.api, actual.api)
 }
@@ -163,7 +181,7 @@ func TestNewCustomClient_Positive2(t *testing.T) {
 	api := &ClientApi{Host: "https://mail.ru", Version: "v2"}
 	actual, _ := NewCustomClient("roleId", "secretId", cliOpt, api)
 	assert.Equal(t, expect.credentials, actual.credentials)
-	assert.Equal(t, expect.actions, actual.actions)
+	assertActionsEqual(t, expect.actions, actual.actions)
 	assert.Equal(t, expect.options, actual.options)
 	assert.Equal(t, expect.api, actual.api)
 }
@@ -179,6 +197,23 @@ func TestNewBasicClient_Negative1(t *testing.T) {
 	assert.Error(t, err, "")
 }
 
+func TestNewBasicClient_EmptyCredentials(t *testing.T) {
+	file, _ := ioutil.TempFile("", "")
+	defer os.Remove(file.Name())
+	_ = ioutil.WriteFile(file.Name(), []byte(certTestVault), 0644)
+
+	store := NewMemoryTokenStore()
+	_ = store.Save(&TokenInfo{Token: "stored_token"})
+
+	client, err := NewBasicClient("", "", &ClientOptions{CertFilePath: file.Name(), TokenStore: store})
+	assert.Nil(t, err)
+	assert.Equal(t, credentials{}, client.credentials)
+
+	client, err = NewCustomClient("", "", &ClientOptions{CertFilePath: file.Name()}, nil)
+	assert.Nil(t, err)
+	assert.NotNil(t, client)
+}
+
 func TestNewCustomClient_Negative1(t *testing.T) {
 	cliOpt := &ClientOptions{
 		CertFilePath:  "not_exist_cert.pem",
@@ -190,15 +225,539 @@ func TestNewCustomClient_Negative1(t *testing.T) {
 	assert.Error(t, err, "")
 }
 
-func TestCreateTokenFilePositive1(t *testing.T) {
+func TestNewTokenInfoPositive1(t *testing.T) {
+	issueTime := time.Unix(1600000000, 0)
+	responseData := []byte(`{"auth":{"client_token":"response_token","accessor":"accessor","lease_duration":60,` +
+		`"renewable":true,"policies":["default"],"metadata":{"role_name":"role"},"other":"other"},"data":"data"}`)
+
+	info, err := newTokenInfo(responseData, issueTime)
+	assert.Nil(t, err)
+	assert.Equal(t, &TokenInfo{
+		Token:         "response_token",
+		Accessor:      "accessor",
+		IssueTime:     issueTime,
+		ExpireTime:    issueTime.Add(time.Minute),
+		LeaseDuration: 60,
+		Renewable:     true,
+		Policies:      []string{"default"},
+		Role:          "role",
+	}, info)
+}
+
+func TestNewTokenInfoNegative1(t *testing.T) {
+	_, err := newTokenInfo([]byte(`{"data":"data"}`), time.Now())
+	assert.Error(t, err, "")
+}
+
+func TestAuthMethod(t *testing.T) {
+	assert.Equal(t, "approle", authMethod(authLink))
+	assert.Equal(t, "userpass", authMethod("/auth/userpass/login/user"))
+	assert.Equal(t, "", authMethod("login"))
+}
+
+func newTestVaultServer(t *testing.T, secret http.HandlerFunc) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/auth/approle/login", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"test_token"}}`))
+	})
+	mux.HandleFunc("/v1/auth/token/lookup-self", func(w http.ResponseWriter, req *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"ttl":3600,"renewable":true}}`))
+	})
+	if secret != nil {
+		mux.HandleFunc("/v1/secret/", secret)
+	}
+
+	return httptest.NewServer(mux)
+}
+
+func newTestClient(t *testing.T, server *httptest.Server, options *ClientOptions) *Client {
 	file, _ := ioutil.TempFile("", "")
-	defer os.Remove(file.Name())
+	_ = file.Close()
+	_ = os.Remove(file.Name())
+	t.Cleanup(func() { _ = os.Remove(file.Name()) })
+
+	cliOpt := &ClientOptions{TokenFilePath: file.Name()}
+	if options != nil {
+		*cliOpt = *options
+		cliOpt.TokenFilePath = file.Name()
+	}
+
+	sep := strings.LastIndex(server.URL, ":")
+	api := getBaseClientApi()
+	api.Host, api.Port = server.URL[:sep], server.URL[sep+1:]
+	endpoints, _ := newEndpointPool(api.addresses())
+
+	return &Client{
+		credentials: credentials{RoleId: "roleId", SecretId: "secretId"},
+		options:     cliOpt,
+		actions:     &httpActions{httpClient: server.Client()},
+		api:         api,
+		tokens:      getTokenStore(cliOpt),
+		cache:       newSecretCache(cliOpt.Cache),
+		endpoints:   endpoints,
+	}
+}
+
+func TestClientGetPositive1(t *testing.T) {
+	server := newTestVaultServer(t, func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "test_token", req.Header.Get("X-Vault-Token"))
+		_, _ = w.Write([]byte(`{"data":{"key":"value"}}`))
+	})
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	data, err := client.Get("secret/path")
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"data":{"key":"value"}}`, toJson(t, data))
+}
+
+func TestClientGetCache(t *testing.T) {
+	calls := 0
+	server := newTestVaultServer(t, func(w http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"lease_duration":1,"data":{"key":"value"}}`))
+	})
+	defer server.Close()
+
+	client := newTestClient(t, server, &ClientOptions{
+		Cache: &CacheOptions{TTL: time.Minute, StaleIfError: true},
+	})
+
+	first, err := client.Get("secret/path")
+	assert.Nil(t, err)
+	second, err := client.Get("secret/path")
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, calls)
+
+	client.cache.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+	stale, err := client.Get("secret/path")
+	assert.Nil(t, err)
+	assert.Equal(t, first, stale)
+	assert.Equal(t, 2, calls)
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2, StaleHits: 1}, client.CacheStats())
+}
+
+func TestClientGetCacheNotMasked(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+	}{
+		{name: "forbidden", status: http.StatusForbidden},
+		{name: "notFound", status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			server := newTestVaultServer(t, func(w http.ResponseWriter, req *http.Request) {
+				calls++
+				if calls > 1 {
+					w.WriteHeader(tc.status)
+					return
+				}
+				_, _ = w.Write([]byte(`{"lease_duration":1,"data":{"key":"value"}}`))
+			})
+			defer server.Close()
+
+			client := newTestClient(t, server, &ClientOptions{
+				Cache: &CacheOptions{TTL: time.Minute, StaleIfError: true},
+			})
+
+			_, err := client.Get("secret/path")
+			assert.Nil(t, err)
+
+			client.cache.now = func() time.Time { return time.Now().Add(2 * time.Second) }
+			data, err := client.Get("secret/path")
+			assert.Nil(t, data)
+			assert.Equal(t, &ResponseError{StatusCode: tc.status, Status: fmt.Sprintf("%d %s", tc.status, http.StatusText(tc.status))}, err)
+			assert.Equal(t, uint64(0), client.CacheStats().StaleHits)
+		})
+	}
+}
+
+func toJson(t *testing.T, data interface{}) string {
+	b, err := json.Marshal(data)
+	assert.Nil(t, err)
+	return string(b)
+}
+
+type testVaultCalls struct {
+	login, lookup, renew, secret int32
+}
+
+func newTestVaultCountingServer(t *testing.T, calls *testVaultCalls, secretStatus int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/auth/approle/login":
+			atomic.AddInt32(&calls.login, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"new_token","lease_duration":3600,"renewable":true}}`))
+		case "/v1/auth/token/renew-self":
+			atomic.AddInt32(&calls.renew, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"renewed_token","lease_duration":3600,"renewable":true}}`))
+		case "/v1/auth/token/lookup-self":
+			atomic.AddInt32(&calls.lookup, 1)
+			_, _ = w.Write([]byte(`{"data":{"accessor":"accessor","ttl":3600,"renewable":true,"meta":{"role_name":"role"}}}`))
+		default:
+			if atomic.AddInt32(&calls.secret, 1) == 1 && secretStatus != 0 {
+				w.WriteHeader(secretStatus)
+				return
+			}
+			_, _ = w.Write([]byte(`{"data":{}}`))
+		}
+	}))
+}
+
+func TestClientTokenMetadata(t *testing.T) {
+	testCases := []struct {
+		name   string
+		stored *TokenInfo
+		token  string
+		calls  testVaultCalls
+	}{
+		{
+			name:   "validWithoutLookup",
+			stored: &TokenInfo{Token: "stored_token", ExpireTime: time.Now().Add(time.Hour), Renewable: true},
+			token:  "stored_token",
+		},
+		{
+			name:   "renewBeforeExpiry",
+			stored: &TokenInfo{Token: "stored_token", ExpireTime: time.Now().Add(time.Minute), Renewable: true},
+			token:  "renewed_token",
+			calls:  testVaultCalls{renew: 1},
+		},
+		{
+			name:   "notRenewableNearExpiry",
+			stored: &TokenInfo{Token: "stored_token", ExpireTime: time.Now().Add(time.Minute)},
+			token:  "stored_token",
+		},
+		{
+			name:   "notRenewableExpiring",
+			stored: &TokenInfo{Token: "stored_token", ExpireTime: time.Now().Add(5 * time.Second)},
+			token:  "new_token",
+			calls:  testVaultCalls{login: 1},
+		},
+		{
+			name:   "expired",
+			stored: &TokenInfo{Token: "stored_token", ExpireTime: time.Now().Add(-time.Minute), Renewable: true},
+			token:  "new_token",
+			calls:  testVaultCalls{login: 1},
+		},
+		{
+			name:   "bareToken",
+			stored: &TokenInfo{Token: "stored_token"},
+			token:  "stored_token",
+			calls:  testVaultCalls{lookup: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls testVaultCalls
+			server := newTestVaultCountingServer(t, &calls, 0)
+			defer server.Close()
+
+			client := newTestClient(t, server, nil)
+			_ = client.tokens.Save(tc.stored)
+
+			token, err := client.token(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.token, *token)
+			assert.Equal(t, tc.calls, calls)
+		})
+	}
+}
+
+func TestClientTokenUpgradeBareFile(t *testing.T) {
+	var calls testVaultCalls
+	server := newTestVaultCountingServer(t, &calls, 0)
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	_ = ioutil.WriteFile(client.options.TokenFilePath, []byte("stored_token"), 0644)
+
+	_, err := client.token(context.Background())
+	assert.Nil(t, err)
+	_, err = client.token(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), calls.lookup)
+
+	info, _ := client.tokens.Load()
+	assert.Equal(t, "stored_token", info.Token)
+	assert.Equal(t, "accessor", info.Accessor)
+	assert.Equal(t, "role", info.Role)
+	assert.True(t, info.Renewable)
+	assert.Equal(t, "approle", info.AuthMethod)
+}
+
+func TestClientGetForbiddenReauth(t *testing.T) {
+	testCases := []struct {
+		name         string
+		lookupStatus int
+		stored       string
+		token        string
+		calls        testVaultCalls
+		err          bool
+	}{
+		{
+			name: "revokedToken", lookupStatus: http.StatusForbidden, stored: "revoked_token", token: "new_token",
+			calls: testVaultCalls{login: 1, lookup: 1, secret: 2},
+		},
+		{
+			name: "policyDenied", stored: "valid_token", token: "valid_token",
+			calls: testVaultCalls{lookup: 1, secret: 1}, err: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls testVaultCalls
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/v1/auth/approle/login":
+					atomic.AddInt32(&calls.login, 1)
+					_, _ = w.Write([]byte(`{"auth":{"client_token":"new_token","lease_duration":3600,"renewable":true}}`))
+				case "/v1/auth/token/lookup-self":
+					atomic.AddInt32(&calls.lookup, 1)
+					if tc.lookupStatus != 0 {
+						w.WriteHeader(tc.lookupStatus)
+						return
+					}
+					_, _ = w.Write([]byte(`{"data":{"ttl":3600,"renewable":true}}`))
+				default:
+					if atomic.AddInt32(&calls.secret, 1) == 1 {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					_, _ = w.Write([]byte(`{"data":{}}`))
+				}
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server, nil)
+			_ = client.tokens.Save(&TokenInfo{Token: tc.stored, ExpireTime: time.Now().Add(time.Hour)})
+
+			_, err := client.Get("secret/path")
+			assert.Equal(t, tc.err, isForbidden(err))
+			assert.Equal(t, tc.calls, calls)
+
+			info, _ := client.tokens.Load()
+			assert.Equal(t, tc.token, info.Token)
+		})
+	}
+}
+
+func TestClientLogout(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		stored  bool
+		revoked int32
+		deleted bool
+		err     bool
+	}{
+		{name: "revoke", status: http.StatusNoContent, stored: true, revoked: 1, deleted: true},
+		{name: "alreadyRevoked", status: http.StatusForbidden, stored: true, revoked: 1, deleted: true},
+		{name: "vaultError", status: http.StatusInternalServerError, stored: true, revoked: 1, err: true},
+		{name: "noToken", status: http.StatusNoContent, deleted: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var revoked int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "/v1/auth/token/revoke-self", req.URL.Path)
+				assert.Equal(t, "POST", req.Method)
+				assert.Equal(t, "stored_token", req.Header.Get("X-Vault-Token"))
+				atomic.AddInt32(&revoked, 1)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server, nil)
+			if tc.stored {
+				_ = client.tokens.Save(&TokenInfo{Token: "stored_token"})
+			}
+
+			err := client.Logout(context.Background())
+			if tc.err {
+				assert.Error(t, err, "")
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tc.revoked, revoked)
+
+			_, err = client.tokens.Load()
+			assert.Equal(t, tc.deleted, err == ErrTokenNotFound)
+		})
+	}
+}
+
+func TestClientLogoutStaticToken(t *testing.T) {
+	var revoked []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/v1/auth/token/revoke-self", req.URL.Path)
+		revoked = append(revoked, req.Header.Get("X-Vault-Token"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, &ClientOptions{Token: "static_token"})
+	_ = client.tokens.Save(&TokenInfo{Token: "stored_token"})
+
+	assert.Nil(t, client.Logout(context.Background()))
+	assert.Equal(t, []string{"static_token"}, revoked)
+
+	info, err := client.tokens.Load()
+	assert.Nil(t, err)
+	assert.Equal(t, "stored_token", info.Token)
+}
+
+func TestClientClose(t *testing.T) {
+	for _, revokeOnClose := range []bool{false, true} {
+		var revoked int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			atomic.AddInt32(&revoked, 1)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+		client := newTestClient(t, server, &ClientOptions{RevokeOnClose: revokeOnClose})
+		_ = client.tokens.Save(&TokenInfo{Token: "stored_token"})
+
+		assert.Nil(t, client.Close())
+		_, err := client.tokens.Load()
+		assert.Equal(t, revokeOnClose, err == ErrTokenNotFound)
+		assert.Equal(t, revokeOnClose, revoked == 1)
+		server.Close()
+	}
+}
+
+func TestClientRenewSelf(t *testing.T) {
+	testCases := []struct {
+		name      string
+		increment time.Duration
+		body      string
+		lease     int
+		status    int
+		token     string
+		login     int32
+	}{
+		{name: "extended", lease: 3600, body: `{}`, token: "stored_token"},
+		{name: "increment", increment: time.Hour, lease: 3600, body: `{"increment":"3600s"}`, token: "stored_token"},
+		{name: "cappedByMaxTtl", increment: time.Hour, lease: 120, body: `{"increment":"3600s"}`, token: "new_token", login: 1},
+		{name: "notExtended", lease: 30, body: `{}`, token: "new_token", login: 1},
+		{name: "renewFailed", status: http.StatusForbidden, body: `{}`, token: "new_token", login: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var login int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/v1/auth/approle/login":
+					atomic.AddInt32(&login, 1)
+					_, _ = w.Write([]byte(`{"auth":{"client_token":"new_token","lease_duration":3600,"renewable":true}}`))
+				case "/v1/auth/token/renew-self":
+					body, _ := ioutil.ReadAll(req.Body)
+					assert.Equal(t, tc.body, string(body))
+					assert.Equal(t, "stored_token", req.Header.Get("X-Vault-Token"))
+					if tc.status != 0 {
+						w.WriteHeader(tc.status)
+						return
+					}
+					_, _ = w.Write([]byte(fmt.Sprintf(`{"auth":{"client_token":"stored_token","lease_duration":%d,"renewable":true}}`, tc.lease)))
+				}
+			}))
+			defer server.Close()
+
+			client := newTestClient(t, server, &ClientOptions{Renewal: RenewalPolicy{Increment: tc.increment}})
+			_ = client.tokens.Save(&TokenInfo{
+				Token:      "stored_token",
+				IssueTime:  time.Now().Add(-time.Hour),
+				ExpireTime: time.Now().Add(time.Minute),
+				Renewable:  true,
+			})
+
+			token, err := client.token(context.Background())
+			assert.Nil(t, err)
+			assert.Equal(t, tc.token, *token)
+			assert.Equal(t, tc.login, login)
+
+			info, _ := client.tokens.Load()
+			assert.True(t, info.Ttl(time.Now()) > 10*time.Minute)
+		})
+	}
+}
+
+func TestClientRenewSelfRepeated(t *testing.T) {
+	var login, renew int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/auth/approle/login":
+			atomic.AddInt32(&login, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"new_token","lease_duration":300,"renewable":true}}`))
+		case "/v1/auth/token/renew-self":
+			atomic.AddInt32(&renew, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"stored_token","lease_duration":300,"renewable":true}}`))
+		}
+	}))
+	defer server.Close()
+
+	client := newTestClient(t, server, nil)
+	now := time.Now()
+	_ = client.tokens.Save(&TokenInfo{
+		Token:         "stored_token",
+		IssueTime:     now,
+		ExpireTime:    now.Add(5 * time.Minute),
+		LeaseDuration: 300,
+		Renewable:     true,
+	})
+
+	// each step moves the stored token 200s into the past: 100s left is inside the 150s grace period
+	for i := 0; i < 10; i++ {
+		info, _ := client.tokens.Load()
+		info.IssueTime = info.IssueTime.Add(-200 * time.Second)
+		info.ExpireTime = info.ExpireTime.Add(-200 * time.Second)
+		_ = client.tokens.Save(info)
+
+		token, err := client.token(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, "stored_token", *token)
+	}
+
+	assert.Equal(t, int32(10), renew)
+	assert.Equal(t, int32(0), login)
+}
+
+func TestClientRenewSelfCapped(t *testing.T) {
+	var login int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/v1/auth/approle/login":
+			atomic.AddInt32(&login, 1)
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"new_token","lease_duration":300,"renewable":true}}`))
+		case "/v1/auth/token/renew-self":
+			_, _ = w.Write([]byte(`{"auth":{"client_token":"stored_token","lease_duration":200,"renewable":true}}`))
+		}
+	}))
+	defer server.Close()
 
-	responseData := []byte(`{"auth":{"client_token":"response_token","other":"other"},"data":"data"}`)
-	token, err := createTokenFile(responseData, file.Name())
-	data, _ := ioutil.ReadFile(file.Name())
+	client := newTestClient(t, server, nil)
+	_ = client.tokens.Save(&TokenInfo{
+		Token:         "stored_token",
+		IssueTime:     time.Now().Add(-200 * time.Second),
+		ExpireTime:    time.Now().Add(100 * time.Second),
+		LeaseDuration: 300,
+		Renewable:     true,
+	})
 
+	token, err := client.token(context.Background())
 	assert.Nil(t, err)
-	assert.Equal(t, "response_token", *token)
-	assert.Equal(t, "response_token", string(data))
+	assert.Equal(t, "new_token", *token)
+	assert.Equal(t, int32(1), login)
 }